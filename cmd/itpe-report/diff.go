@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/explorerray/itpe-report/config"
+	"github.com/explorerray/itpe-report/internal/diff"
+	"github.com/explorerray/itpe-report/internal/exporter/dataset"
+)
+
+// runDiff compares two exported result sets and returns the exit code:
+// 0 without regression, 1 with regressions (or missing experiments and metrics) and 2 on failure
+func runDiff(dc config.DiffConf, logger *slog.Logger) int {
+	baseline, err := dataset.Load(dc.Baseline)
+	if err != nil {
+		logger.Error("Failed to load baseline", "path", dc.Baseline, "error", err)
+		return 2
+	}
+	candidate, err := dataset.Load(dc.Candidate)
+	if err != nil {
+		logger.Error("Failed to load candidate", "path", dc.Candidate, "error", err)
+		return 2
+	}
+
+	res := diff.Compare(baseline, candidate, diff.Thresholds{Default: dc.Tolerance, PerMetric: dc.Thresholds})
+	diff.RenderTable(res, dc.ShowAll, os.Stdout)
+	if dc.HTMLPath != "" {
+		if err := diff.WriteHTML(res, dc.HTMLPath); err != nil {
+			logger.Error("Failed to write HTML diff", "error", err)
+			return 2
+		}
+		logger.Info("HTML diff generated", "path", dc.HTMLPath)
+	}
+
+	if res.Regressions > 0 {
+		return 1
+	}
+	if dc.FailOnMissing && (res.Lost > 0 || len(res.MissingInCandidate) > 0) {
+		logger.Error("Candidate lacks results of the baseline", "metrics", res.Lost, "experiments", len(res.MissingInCandidate))
+		return 1
+	}
+	return 0
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/explorerray/itpe-report/config"
 	"github.com/explorerray/itpe-report/internal/client/promclient"
-	"github.com/explorerray/itpe-report/internal/exporter/plot"
+	"github.com/explorerray/itpe-report/internal/exporter"
 	"github.com/explorerray/itpe-report/internal/input"
 	"github.com/explorerray/itpe-report/internal/logger"
 )
@@ -13,24 +18,127 @@ import (
 func main() {
 	logger := logger.NewLogger(logger.LogLevel(), os.Stdout)
 	c := config.ParseArgsAndConfig(logger)
+	// Interrupting stops the pending queries
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if c.Command == config.CommandDiff {
+		os.Exit(runDiff(c.ReportConf.Diff, logger))
+	}
+
+	if err := input.SetCheckpointPattern(c.ReportConf.CheckpointPattern); err != nil {
+		logger.Error("Failed to set the checkpoint pattern", "error", err)
+		os.Exit(1)
+	}
+
+	exporters, err := exporter.New(c.ReportConf.Outputs, *c, logger)
+	if err != nil {
+		logger.Error("Failed to create exporters", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize Prometheus client, only needed when Kepler is the energy source
+	if t := c.ReportConf.EnergySource.Type; t == "" || t == config.EnergySourceKepler {
+		if err := promclient.Init(c.ReportConf.PrometheusURL, promClientOptions(c.ReportConf.PromClient)); err != nil {
+			logger.Error("Failed to initialize Prometheus client", "error", err)
+			os.Exit(1)
+		}
+	}
 
-	// Initialize Prometheus client
-	if err := promclient.Init(c.ReportConf.PrometheusURL); err != nil {
-		logger.Error("Failed to initialize Prometheus client", "error", err)
+	if c.ReportConf.Watch <= 0 {
+		if _, _, err := report(ctx, *c, exporters, logger); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := watch(ctx, *c, exporters, logger); err != nil {
+		logger.Error("Failed to watch the artifacts directory", "error", err)
 		os.Exit(1)
 	}
+}
 
+// report generates the report of the profiles currently in the artifacts directory and returns
+// its metrics and the number of experiments still running, failures are logged
+func report(ctx context.Context, c config.Config, exporters []exporter.Exporter, logger *slog.Logger) (input.ExpMetricPair, int, error) {
 	// Read & parse GenAIperf json, then generate experiment metrics mapping
-	emp, err := input.GenExpMetricPair(*c, logger)
+	emp, err := input.GenExpMetricPair(ctx, c, logger)
 	if err != nil {
 		logger.Error("Failed to parse experiment metrics", "error", err)
-		os.Exit(1)
+		return nil, 0, err
 	}
 	logger.Info("Experiment metrics parsed")
-	// Gen plots into png
-	plotDir := plot.CreatePlotsSubdir(*c)
-	if err := plot.GeneratePlots(emp, plotDir, logger); err != nil {
-		logger.Error("Failed to generate plots", "error", err)
-		os.Exit(1)
+
+	for _, e := range exporters {
+		if err := e.Export(emp); err != nil {
+			logger.Error("Failed to export", "output", e.Name(), "error", err)
+			return nil, 0, err
+		}
+	}
+
+	pending, err := input.PendingExperiments(c, emp)
+	if err != nil {
+		logger.Warn("Cannot tell the pending experiments", "error", err)
+	} else if pending > 0 {
+		logger.Info("Report covers a partial sweep", "pending", pending)
+	}
+	return emp, pending, nil
+}
+
+// watch regenerates the report every time profiles or checkpoints land in the artifacts directory,
+// until the sweep is complete or ctx is done
+func watch(ctx context.Context, c config.Config, exporters []exporter.Exporter, logger *slog.Logger) error {
+	dir := c.ReportConf.ArtfDir
+	for {
+		// Taken before reading the profiles so that the ones landing meanwhile trigger the next report
+		state, err := input.ArtifactsState(dir)
+		if err != nil {
+			return err
+		}
+		emp, pending, err := report(ctx, c, exporters, logger)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil && pending == 0 {
+			// The net energy is reported once the idle window after the sweep is over
+			until := input.IdleBaselineEnd(c, emp)
+			if !time.Now().Before(until) {
+				logger.Info("Sweep complete, stopped watching", "dir", dir)
+				return nil
+			}
+			logger.Info("Sweep complete, waiting for the idle window after it", "until", until)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Until(until) + c.ReportConf.Watch):
+			}
+			continue
+		}
+		// Without a report yet, the profiles may not have landed
+		logger.Info("Watching for profiles and checkpoints", "dir", dir, "interval", c.ReportConf.Watch)
+		if err := input.WaitForChanges(ctx, dir, state, c.ReportConf.Watch); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// promClientOptions maps the Prometheus client config onto the client options
+func promClientOptions(pc config.PromClientConf) promclient.ClientOptions {
+	return promclient.ClientOptions{
+		BearerToken:        pc.BearerToken,
+		BearerTokenFile:    pc.BearerTokenFile,
+		Username:           pc.BasicAuth.Username,
+		Password:           pc.BasicAuth.Password,
+		PasswordFile:       pc.BasicAuth.PasswordFile,
+		CAFile:             pc.TLS.CAFile,
+		CertFile:           pc.TLS.CertFile,
+		KeyFile:            pc.TLS.KeyFile,
+		ServerName:         pc.TLS.ServerName,
+		InsecureSkipVerify: pc.TLS.InsecureSkipVerify,
+		Headers:            pc.Headers,
+		MaxRetries:         pc.MaxRetries,
+		RetryBackoff:       pc.RetryBackoff,
+		QueryTimeout:       pc.QueryTimeout,
 	}
 }
@@ -4,17 +4,40 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"gopkg.in/yaml.v3"
 )
 
+// Commands
+const (
+	CommandReport = "report"
+	CommandDiff   = "diff"
+)
+
 type (
 	Config struct {
 		ConfigPath string
+		Command    string
 		ReportConf ReportConf `yaml:"itpe_report"`
 		GenAIPerf  GenAIPerf  `yaml:"itpe_perf"`
 	}
+
+	// flags holds the command line flags overriding the config file
+	flags struct {
+		configSet    bool
+		outputs      string
+		discover     bool
+		watch        time.Duration
+		parallelism  int
+		diff         DiffConf
+		toleranceSet bool
+		missingSet   bool
+		thresholds   map[string]string
+	}
 )
 
 func DefaultConfig() *Config {
@@ -23,15 +46,119 @@ func DefaultConfig() *Config {
 		ReportConf: ReportConf{
 			PrometheusURL: "http://localhost:9090",
 			ArtfDir:       "/artifacts",
+			Outputs:       []string{"plots", "html", "csv", "jsonl", "parquet"},
+			PromClient: PromClientConf{
+				MaxRetries:   3,
+				RetryBackoff: 500 * time.Millisecond,
+				QueryTimeout: 30 * time.Second,
+			},
+			Diff: DiffConf{
+				Tolerance:     5,
+				FailOnMissing: true,
+			},
+			EnergySource: EnergySourceConf{
+				Type: EnergySourceKepler,
+			},
+			KeplerSchema:      "auto",
+			CheckpointPattern: DefaultCheckpointPattern,
+			IdleBaseline: IdleBaselineConf{
+				Position: IdleBefore,
+				Margin:   30 * time.Second,
+			},
+			SteadyState: SteadyStateConf{
+				Window: WindowFull,
+			},
+			PowerSeries: PowerSeriesConf{
+				Step:       5 * time.Second,
+				RateWindow: 30 * time.Second,
+			},
+			Footprint: FootprintConf{
+				PUE:      1,
+				Currency: "USD",
+				CarbonIntensityCSV: CarbonIntensityCSVConf{
+					TimestampColumn: "timestamp",
+					Column:          "carbon_intensity",
+				},
+			},
+			QueryPadding: time.Minute,
+			Pipeline: PipelineConf{
+				Parallelism: 4,
+				BatchWindow: time.Hour,
+			},
+			Tokenizer: TokenizerApprox,
 		},
 		GenAIPerf: GenAIPerf{
 			EndpointURL: "http://localhost:8000",
+			Enabled: Enabled{
+				// Mode of the profiles whose payload has no stream field
+				Stream: true,
+			},
 		},
 	}
 }
 
-func RegisterFlags(app *kingpin.Application, config *Config) {
-	app.Flag("config", "Path to config file").StringVar(&config.ConfigPath)
+func RegisterFlags(app *kingpin.Application, config *Config, f *flags) {
+	app.Flag("config", "Path to config file").IsSetByUser(&f.configSet).StringVar(&config.ConfigPath)
+	app.Flag("output", "Comma-separated outputs to generate: plots,table,html,csv,jsonl,parquet (overrides itpe_report.outputs)").StringVar(&f.outputs)
+
+	app.Flag("discover", "Discover the GenAI-Perf profiles under the artifacts directory instead of generating their paths from the config").BoolVar(&f.discover)
+
+	app.Flag("watch", "Regenerate the report at this interval as profiles and checkpoints land (overrides itpe_report.watch)").DurationVar(&f.watch)
+
+	app.Flag("parallelism", "Profiles parsed and experiments measured at once (overrides itpe_report.pipeline.parallelism)").IntVar(&f.parallelism)
+
+	app.Command(CommandReport, "Generate the report of an experiment sweep").Default()
+
+	diff := app.Command(CommandDiff, "Compare two exported result sets, exit with 1 on regressions")
+	diff.Arg("baseline", "Baseline results.jsonl/results.csv, or the artifacts directory containing it").Required().StringVar(&f.diff.Baseline)
+	diff.Arg("candidate", "Candidate results.jsonl/results.csv, or the artifacts directory containing it").Required().StringVar(&f.diff.Candidate)
+	diff.Flag("tolerance", "Relative change tolerated before a metric is flagged, in percent (overrides itpe_report.diff.tolerance)").IsSetByUser(&f.toleranceSet).Float64Var(&f.diff.Tolerance)
+	diff.Flag("threshold", "Per-metric tolerance in percent as column=percent, repeatable").StringMapVar(&f.thresholds)
+	diff.Flag("html", "Also write the comparison as HTML to this path").StringVar(&f.diff.HTMLPath)
+	diff.Flag("all", "Show every metric instead of the flagged ones only").BoolVar(&f.diff.ShowAll)
+	diff.Flag("fail-on-missing", "Exit with 1 when the candidate lacks an experiment or a metric of the baseline, on by default (overrides itpe_report.diff.fail_on_missing)").IsSetByUser(&f.missingSet).BoolVar(&f.diff.FailOnMissing)
+}
+
+// apply overrides the config with the flags that were set
+func (f flags) apply(config *Config) error {
+	d := &config.ReportConf.Diff
+	d.Baseline, d.Candidate = f.diff.Baseline, f.diff.Candidate
+	d.HTMLPath, d.ShowAll = f.diff.HTMLPath, f.diff.ShowAll
+	if f.toleranceSet {
+		d.Tolerance = f.diff.Tolerance
+	}
+	if f.missingSet {
+		d.FailOnMissing = f.diff.FailOnMissing
+	}
+	for metric, v := range f.thresholds {
+		pct, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid threshold %s=%s: %v", metric, v, err)
+		}
+		if d.Thresholds == nil {
+			d.Thresholds = make(map[string]float64)
+		}
+		d.Thresholds[metric] = pct
+	}
+
+	if f.discover {
+		config.ReportConf.Discover = true
+	}
+	if f.watch > 0 {
+		config.ReportConf.Watch = f.watch
+	}
+	if f.parallelism > 0 {
+		config.ReportConf.Pipeline.Parallelism = f.parallelism
+	}
+	if f.outputs != "" {
+		config.ReportConf.Outputs = nil
+		for _, o := range strings.Split(f.outputs, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				config.ReportConf.Outputs = append(config.ReportConf.Outputs, o)
+			}
+		}
+	}
+	return nil
 }
 
 func loadConfig(path string) (*Config, error) {
@@ -40,11 +167,13 @@ func loadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read YAML file %s: %v", path, err)
 	}
 
-	var conf Config
-	if err := yaml.Unmarshal(data, &conf); err != nil {
+	// Start from the defaults so that omitted fields keep their default value
+	conf := DefaultConfig()
+	conf.ConfigPath = path
+	if err := yaml.Unmarshal(data, conf); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML file %s: %v", path, err)
 	}
-	return &conf, nil
+	return conf, nil
 }
 
 func ParseArgsAndConfig(logger *slog.Logger) *Config {
@@ -52,15 +181,22 @@ func ParseArgsAndConfig(logger *slog.Logger) *Config {
 	app := kingpin.New(appName, "ITPE report tool - Used to generate report for inference perf & energy")
 
 	config := DefaultConfig()
+	f := flags{thresholds: make(map[string]string)}
 
-	RegisterFlags(app, config)
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	RegisterFlags(app, config, &f)
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	config, err := loadConfig(config.ConfigPath)
-	if err != nil {
-		logger.Error("Failed to load config", "error", err)
-		return DefaultConfig()
+	// The config file is optional for diff
+	if command != CommandDiff || f.configSet {
+		loaded, err := loadConfig(config.ConfigPath)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			loaded = DefaultConfig()
+		}
+		config = loaded
 	}
+	config.Command = command
 
+	app.FatalIfError(f.apply(config), "")
 	return config
 }
@@ -0,0 +1,18 @@
+package config
+
+// DiffConf configures the comparison of two exported result sets by the diff command
+type DiffConf struct {
+	// Result sets to compare, from the command line
+	Baseline  string `yaml:"-"`
+	Candidate string `yaml:"-"`
+	// Optional HTML output path, from the command line
+	HTMLPath string `yaml:"-"`
+	// Show every metric instead of the flagged ones only, from the command line
+	ShowAll bool `yaml:"-"`
+	// Relative change tolerated before a metric is flagged (unit: percent)
+	Tolerance float64 `yaml:"tolerance"`
+	// Per-metric tolerance keyed by dataset column, e.g. avg_ttft_ms: 10
+	Thresholds map[string]float64 `yaml:"thresholds"`
+	// Fail when the candidate lacks an experiment or a metric of the baseline
+	FailOnMissing bool `yaml:"fail_on_missing"`
+}
@@ -13,8 +13,8 @@ type (
 	}
 
 	Enabled struct {
-		Stream     bool `yaml:"stream"`
-		Checkpoint bool `yaml:"checkpoint"`
+		Stream     bool `yaml:"stream"`     // Responses streamed, unless the payload of the profile tells otherwise
+		Checkpoint bool `yaml:"checkpoint"` // Read the experiments still running from their checkpoints
 	}
 
 	Requests struct {
@@ -22,11 +22,13 @@ type (
 	}
 
 	GenAIPerf struct {
-		EndpointURL string     `yaml:"url"`
-		Enabled     Enabled    `yaml:"enabled"`
-		Models      []string   `yaml:"models"`
-		Concurrency []int      `yaml:"concurrency"`
-		Requests    Requests   `yaml:"requests"`
-		TokenConfs  TokenConfs `yaml:"token_confs"`
+		EndpointURL string   `yaml:"url"`
+		Enabled     Enabled  `yaml:"enabled"`
+		Models      []string `yaml:"models"`
+		Concurrency []int    `yaml:"concurrency"`
+		// Offered request rates of the open loop experiments (unit: requests per second)
+		RequestRates []float64  `yaml:"request_rates"`
+		Requests     Requests   `yaml:"requests"`
+		TokenConfs   TokenConfs `yaml:"token_confs"`
 	}
 )
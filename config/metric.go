@@ -2,6 +2,7 @@ package config
 
 // metricConfig defines plot settings for a metric
 type metricConfig struct {
+	Name     string
 	YLabel   string
 	Filename string
 }
@@ -13,51 +14,231 @@ type YPlot struct {
 	AvgRequestLatencyMs   float64
 	AvgTTFTMs             float64
 	AvgITLMs              float64
+	// TTFT distribution
+	P50TTFTMs    float64
+	P90TTFTMs    float64
+	P95TTFTMs    float64
+	P99TTFTMs    float64
+	MinTTFTMs    float64
+	MaxTTFTMs    float64
+	StddevTTFTMs float64
+	// ITL distribution
+	P50ITLMs    float64
+	P90ITLMs    float64
+	P95ITLMs    float64
+	P99ITLMs    float64
+	MinITLMs    float64
+	MaxITLMs    float64
+	StddevITLMs float64
+	// Request Latency distribution
+	P50RequestLatencyMs    float64
+	P90RequestLatencyMs    float64
+	P95RequestLatencyMs    float64
+	P99RequestLatencyMs    float64
+	MinRequestLatencyMs    float64
+	MaxRequestLatencyMs    float64
+	StddevRequestLatencyMs float64
 	// power
 	NodePlatformJ  float64
 	NodeGPUJ       float64
 	NodeCPUJ       float64
 	EnergyPerToken float64
+	// power net of idle
+	NetNodePlatformJ  float64
+	NetEnergyPerToken float64
+	// carbon and cost
+	CarbonPerMTokens float64
+	CostPerMTokens   float64
+}
+
+// metricsConfig lists the plotted metrics in display order
+var metricsConfig = []metricConfig{
+	{
+		Name:     "Request Throughput",
+		YLabel:   "Requests per Second",
+		Filename: "req_throughput",
+	},
+	{
+		Name:     "Output Token Throughput",
+		YLabel:   "Tokens per Second",
+		Filename: "out_token_throughput",
+	},
+	{
+		Name:     "Avg Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "avg_req_latency",
+	},
+	{
+		Name:     "Avg TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "avg_ttft",
+	},
+	{
+		Name:     "Avg ITL",
+		YLabel:   "Milliseconds",
+		Filename: "avg_itl",
+	},
+	{
+		Name:     "P50 TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "p50_ttft",
+	},
+	{
+		Name:     "P90 TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "p90_ttft",
+	},
+	{
+		Name:     "P95 TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "p95_ttft",
+	},
+	{
+		Name:     "P99 TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "p99_ttft",
+	},
+	{
+		Name:     "Min TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "min_ttft",
+	},
+	{
+		Name:     "Max TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "max_ttft",
+	},
+	{
+		Name:     "Stddev TTFT",
+		YLabel:   "Milliseconds",
+		Filename: "stddev_ttft",
+	},
+	{
+		Name:     "P50 ITL",
+		YLabel:   "Milliseconds",
+		Filename: "p50_itl",
+	},
+	{
+		Name:     "P90 ITL",
+		YLabel:   "Milliseconds",
+		Filename: "p90_itl",
+	},
+	{
+		Name:     "P95 ITL",
+		YLabel:   "Milliseconds",
+		Filename: "p95_itl",
+	},
+	{
+		Name:     "P99 ITL",
+		YLabel:   "Milliseconds",
+		Filename: "p99_itl",
+	},
+	{
+		Name:     "Min ITL",
+		YLabel:   "Milliseconds",
+		Filename: "min_itl",
+	},
+	{
+		Name:     "Max ITL",
+		YLabel:   "Milliseconds",
+		Filename: "max_itl",
+	},
+	{
+		Name:     "Stddev ITL",
+		YLabel:   "Milliseconds",
+		Filename: "stddev_itl",
+	},
+	{
+		Name:     "P50 Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "p50_req_latency",
+	},
+	{
+		Name:     "P90 Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "p90_req_latency",
+	},
+	{
+		Name:     "P95 Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "p95_req_latency",
+	},
+	{
+		Name:     "P99 Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "p99_req_latency",
+	},
+	{
+		Name:     "Min Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "min_req_latency",
+	},
+	{
+		Name:     "Max Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "max_req_latency",
+	},
+	{
+		Name:     "Stddev Request Latency",
+		YLabel:   "Milliseconds",
+		Filename: "stddev_req_latency",
+	},
+	{
+		Name:     "Node Platform",
+		YLabel:   "Joules",
+		Filename: "node_pltf_energy",
+	},
+	{
+		Name:     "Node GPU",
+		YLabel:   "Joules",
+		Filename: "node_gpu_energy",
+	},
+	{
+		Name:     "Node CPU",
+		YLabel:   "Joules",
+		Filename: "node_cpu_energy",
+	},
+	{
+		Name:     "Energy Per Token",
+		YLabel:   "Joules per Token",
+		Filename: "energy_per_token",
+	},
+	{
+		Name:     "Net Node Platform",
+		YLabel:   "Joules",
+		Filename: "net_node_platform",
+	},
+	{
+		Name:     "Net Energy Per Token",
+		YLabel:   "Joules per Token",
+		Filename: "net_energy_per_token",
+	},
+	{
+		Name:     "Carbon Per 1M Tokens",
+		YLabel:   "gCO2e per 1M Output Tokens",
+		Filename: "carbon_per_1m_tokens",
+	},
+	{
+		Name:     "Cost Per 1M Tokens",
+		YLabel:   "Cost per 1M Output Tokens",
+		Filename: "cost_per_1m_tokens",
+	},
 }
 
 // GetMetricsConfig returns the configuration for all metrics
 func GetMetricsConfig() map[string]metricConfig {
-	return map[string]metricConfig{
-		"Request Throughput": {
-			YLabel:   "Requests per Second",
-			Filename: "req_throughput",
-		},
-		"Output Token Throughput": {
-			YLabel:   "Tokens per Second",
-			Filename: "out_token_throughput",
-		},
-		"Avg Request Latency": {
-			YLabel:   "Milliseconds",
-			Filename: "avg_req_latency",
-		},
-		"Avg TTFT": {
-			YLabel:   "Milliseconds",
-			Filename: "avg_ttft",
-		},
-		"Avg ITL": {
-			YLabel:   "Milliseconds",
-			Filename: "avg_itl",
-		},
-		"Node Platform": {
-			YLabel:   "Joules",
-			Filename: "node_pltf_energy",
-		},
-		"Node GPU": {
-			YLabel:   "Joules",
-			Filename: "node_gpu_energy",
-		},
-		"Node CPU": {
-			YLabel:   "Joules",
-			Filename: "node_cpu_energy",
-		},
-		"Energy Per Token": {
-			YLabel:   "Joules per Token",
-			Filename: "energy_per_token",
-		},
+	m := make(map[string]metricConfig, len(metricsConfig))
+	for _, mc := range metricsConfig {
+		m[mc.Name] = mc
+	}
+	return m
+}
+
+// GetMetricNames returns the names of all metrics in display order
+func GetMetricNames() []string {
+	names := make([]string, len(metricsConfig))
+	for i, mc := range metricsConfig {
+		names[i] = mc.Name
 	}
+	return names
 }
@@ -1,6 +1,242 @@
 package config
 
+import (
+	"strings"
+	"time"
+)
+
+// Supported energy sources
+const (
+	EnergySourceKepler = "kepler" // Kepler counters scraped by Prometheus
+	EnergySourceRAPL   = "rapl"   // RAPL energy_uj readings recorded to a file
+	EnergySourceCSV    = "csv"    // Generic power log in watts
+)
+
+// Supported tokenizers counting the tokens the server does not report
+const (
+	TokenizerApprox = "approx" // About 4 characters per token
+	TokenizerWords  = "words"  // A token per word, number or punctuation mark
+)
+
+type EnergySourceConf struct {
+	Type string `yaml:"type"`
+	// Recorded file for the rapl and csv sources
+	Path string `yaml:"path"`
+	// Column name of each component in the csv source (e.g. node_platform: "total_w"),
+	// defaults to the component name itself
+	Columns map[string]string `yaml:"columns"`
+}
+
+// LabelMatcher is an extra PromQL label matcher
+type LabelMatcher struct {
+	Name  string `yaml:"name"`
+	Type  string `yaml:"type"` // =, !=, =~ or !~ (default: =)
+	Value string `yaml:"value"`
+}
+
+// KeplerSelector selects the pod serving the model in the Kepler metrics
+type KeplerSelector struct {
+	ContainerName string `yaml:"container_name"`
+	PodName       string `yaml:"pod_name"`
+	Namespace     string `yaml:"namespace"`
+	// Extra matchers applied to both node and pod counters (e.g. instance)
+	Labels []LabelMatcher `yaml:"labels"`
+}
+
+// Positions of the idle baseline window relative to the sweep
+const (
+	IdleBefore = "before"
+	IdleAfter  = "after"
+	IdleBoth   = "both"
+)
+
+// IdleBaselineConf sets the quiet windows around the sweep measuring the idle power of the node
+type IdleBaselineConf struct {
+	Duration time.Duration `yaml:"duration"` // Length of each window, 0 disables the baseline
+	Position string        `yaml:"position"` // before, after or both
+	Margin   time.Duration `yaml:"margin"`   // Gap between a window and the sweep
+}
+
+// Steady-state windows of the experiments
+const (
+	WindowFull       = "full"              // From the first request to the last response
+	WindowBoundaries = "window_boundaries" // From the first to the last window boundary of the profile export
+	WindowTrim       = "trim"              // Without the warm-up and cool-down requests
+)
+
+// SteadyStateConf sets the window of each experiment over which its perf and energy are measured,
+// only the requests fully within the window are measured
+type SteadyStateConf struct {
+	Window string `yaml:"window"` // full, window_boundaries or trim
+	// Trimmed with trim: the first requests sent and the last requests completed,
+	// and the time after the first request and before the last response
+	WarmupRequests   int           `yaml:"warmup_requests"`
+	CooldownRequests int           `yaml:"cooldown_requests"`
+	Warmup           time.Duration `yaml:"warmup"`
+	Cooldown         time.Duration `yaml:"cooldown"`
+}
+
+// PowerSeriesConf sets the power time series fetched for each experiment
+type PowerSeriesConf struct {
+	Step time.Duration `yaml:"step"` // Resolution of the series, 0 disables it
+	// Range of rate() over the Kepler counters, should be >= 2 scrape intervals
+	RateWindow time.Duration `yaml:"rate_window"`
+}
+
+// PipelineConf sets how the experiments of the sweep are processed concurrently
+type PipelineConf struct {
+	Parallelism int `yaml:"parallelism"` // Profiles parsed and experiments measured at once, <= 1 is sequential
+	// Longest span of the experiments whose Kepler counters are fetched by a single query per counter,
+	// 0 queries the counters of each experiment
+	BatchWindow time.Duration `yaml:"batch_window"`
+}
+
+// FootprintConf sets the conversion of the energy into carbon emissions and electricity cost
+type FootprintConf struct {
+	PUE float64 `yaml:"pue"` // Power usage effectiveness of the facility, 1 counts the node energy only
+	// Static grid carbon intensity (unit: gCO2e/kWh), 0 leaves the carbon unknown
+	CarbonIntensity float64 `yaml:"carbon_intensity"`
+	// Grid carbon intensity over time, overriding the static one
+	CarbonIntensityCSV CarbonIntensityCSVConf `yaml:"carbon_intensity_csv"`
+	// Electricity price per kWh, 0 leaves the cost unknown
+	PricePerKWh float64 `yaml:"price_per_kwh"`
+	Currency    string  `yaml:"currency"`
+}
+
+// CarbonIntensityCSVConf locates a grid carbon intensity log, each reading holding until the next one
+type CarbonIntensityCSVConf struct {
+	Path            string `yaml:"path"`
+	TimestampColumn string `yaml:"timestamp_column"` // RFC3339 or unix timestamps (default: timestamp)
+	Column          string `yaml:"column"`           // Intensity in gCO2e/kWh (default: carbon_intensity)
+}
+
+// PromClientConf sets the authentication, TLS and headers of the requests sent to Prometheus
+type PromClientConf struct {
+	BearerToken     string        `yaml:"bearer_token"`
+	BearerTokenFile string        `yaml:"bearer_token_file"` // Read on every request
+	BasicAuth       BasicAuthConf `yaml:"basic_auth"`
+	TLS             TLSConf       `yaml:"tls"`
+	// Extra headers, e.g. X-Scope-OrgID selecting the tenant of Mimir, Cortex or Thanos
+	Headers map[string]string `yaml:"headers"`
+	// Retries of the queries failing with a transient error (timeout, 5xx, 429 or network error),
+	// the backoff doubles after each retry
+	MaxRetries   int           `yaml:"max_retries"`
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	// Timeout of each attempt of a query, long enough for the batch_window of raw samples fetched at once
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+}
+
+type BasicAuthConf struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"` // Read on every request
+}
+
+type TLSConf struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"` // Client certificate for mTLS, along with key_file
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// ModelConf holds the settings of a single model, overriding the global ones
+type ModelConf struct {
+	// Metadata overriding the one parsed from the model name
+	Params       string `yaml:"params"` // Parameter count with unit m, b or t (e.g. 270m, 7b)
+	Quantization string `yaml:"quantization"`
+	Family       string `yaml:"family"`
+
+	Kepler KeplerSelector `yaml:"kepler"`
+}
+
+// DefaultCheckpointPattern names the checkpoints of 10_4_profile.json 10_4_profile.checkpoint.json
+// or numbered 10_4_profile.checkpoint-3.json
+const DefaultCheckpointPattern = "{profile}_profile.checkpoint*.json"
+
 type ReportConf struct {
-	PrometheusURL string `yaml:"prom_url"`
-	ArtfDir       string `yaml:"artf_dir"`
+	PrometheusURL string         `yaml:"prom_url"`
+	PromClient    PromClientConf `yaml:"prom_client"`
+	ArtfDir       string         `yaml:"artf_dir"`
+	// Discover the profiles under artf_dir instead of generating their paths from itpe_perf
+	Discover bool `yaml:"discover"`
+	// Regenerate the report at most this often as profiles and checkpoints land in artf_dir,
+	// until the sweep completes or the tool is interrupted. 0 generates it once.
+	Watch time.Duration `yaml:"watch"`
+	// File name pattern of the checkpoints written next to the profile export of a running experiment,
+	// {profile} standing for the profile name without _profile.json and * for any text
+	CheckpointPattern string `yaml:"checkpoint_pattern"`
+	// Outputs to generate: plots, table, html, csv, jsonl and/or parquet
+	Outputs      []string         `yaml:"outputs"`
+	EnergySource EnergySourceConf `yaml:"energy_source"`
+	Kepler       KeplerSelector   `yaml:"kepler"`
+	// Kepler metric schema: auto (probed from Prometheus), v0.8 or v0.10
+	KeplerSchema string `yaml:"kepler_schema"`
+	// Per-model settings keyed by model name, either with tag (llama3.2:1b) or without (llama3.2)
+	Models map[string]ModelConf `yaml:"models"`
+	// Comparison settings of the diff command
+	Diff DiffConf `yaml:"diff"`
+	// Idle power measured around the sweep, subtracted from the energy of the experiments
+	IdleBaseline IdleBaselineConf `yaml:"idle_baseline"`
+	// Power drawn over time during each experiment
+	PowerSeries PowerSeriesConf `yaml:"power_series"`
+	// Carbon emissions and electricity cost of the energy
+	Footprint FootprintConf `yaml:"footprint"`
+	// Extra time fetched around each experiment window so the energy counters
+	// can be interpolated at the window edges, should be >= the scrape interval
+	QueryPadding time.Duration `yaml:"query_padding"`
+	// Window of each experiment over which its perf and energy are measured
+	SteadyState SteadyStateConf `yaml:"steady_state"`
+	// Concurrency of the profile parsing and the Prometheus queries
+	Pipeline PipelineConf `yaml:"pipeline"`
+	// Tokenizer counting the prompt and output tokens when the responses carry no usage:
+	// approx or words
+	Tokenizer string `yaml:"tokenizer"`
+}
+
+// ModelConfFor returns the settings of model, looked up with its tag first and then without it
+func (rc ReportConf) ModelConfFor(model string) (ModelConf, bool) {
+	if mc, ok := rc.Models[model]; ok {
+		return mc, true
+	}
+	if base, _, found := strings.Cut(model, ":"); found {
+		mc, ok := rc.Models[base]
+		return mc, ok
+	}
+	return ModelConf{}, false
+}
+
+// KeplerSelectorFor returns the Kepler selector of model.
+// Non-empty fields of the model override the global ones, and so do its labels with the same name.
+func (rc ReportConf) KeplerSelectorFor(model string) KeplerSelector {
+	ks := rc.Kepler
+	ks.Labels = append([]LabelMatcher{}, rc.Kepler.Labels...)
+	mc, ok := rc.ModelConfFor(model)
+	if !ok {
+		return ks
+	}
+
+	override := mc.Kepler
+	if override.ContainerName != "" {
+		ks.ContainerName = override.ContainerName
+	}
+	if override.PodName != "" {
+		ks.PodName = override.PodName
+	}
+	if override.Namespace != "" {
+		ks.Namespace = override.Namespace
+	}
+	for _, ol := range override.Labels {
+		replaced := false
+		for i, l := range ks.Labels {
+			if l.Name == ol.Name {
+				ks.Labels[i] = ol
+				replaced = true
+			}
+		}
+		if !replaced {
+			ks.Labels = append(ks.Labels, ol)
+		}
+	}
+	return ks
 }
@@ -0,0 +1,18 @@
+#!/bin/sh
+# Record RAPL energy counters for the rapl energy source.
+# Usage: hack/record-rapl.sh <output.csv> [interval_seconds]
+set -eu
+
+OUT=${1:?output file required}
+INTERVAL=${2:-1}
+
+[ -f "$OUT" ] || echo "timestamp,zone,name,energy_uj,max_energy_range_uj" > "$OUT"
+
+while true; do
+	ts=$(date +%s%N)
+	for z in /sys/class/powercap/intel-rapl:*; do
+		[ -r "$z/energy_uj" ] || continue
+		echo "$ts,$(basename "$z"),$(cat "$z/name"),$(cat "$z/energy_uj"),$(cat "$z/max_energy_range_uj")"
+	done >> "$OUT"
+	sleep "$INTERVAL"
+done
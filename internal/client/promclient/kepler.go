@@ -0,0 +1,245 @@
+package promclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Energy components, in the order of KeplerComponents
+const (
+	NodePlatform = "node_platform"
+	NodeGPU      = "node_gpu"
+	NodePackage  = "node_package"
+	NodeDRAM     = "node_dram"
+	NodeOther    = "node_other"
+	PodGPU       = "pod_gpu"
+	PodDRAM      = "pod_dram"
+	PodPackage   = "pod_package"
+	PodPlatform  = "pod_platform"
+	PodOther     = "pod_other"
+)
+
+// KeplerComponents lists every energy component a Kepler schema may expose
+var KeplerComponents = []string{
+	NodePlatform, NodeGPU, NodePackage, NodeDRAM, NodeOther,
+	PodGPU, PodDRAM, PodPackage, PodPlatform, PodOther,
+}
+
+// Supported Kepler schemas
+const (
+	KeplerSchemaAuto = "auto"
+	KeplerSchemaV08  = "v0.8"  // Kepler <= 0.9, one counter per component
+	KeplerSchemaV010 = "v0.10" // Kepler >= 0.10, CPU counters split by RAPL zone label
+)
+
+// KeplerCounter is the counter of one energy component
+type KeplerCounter struct {
+	Metric   string
+	Matchers []Matcher // Fixed matchers, e.g. the RAPL zone
+}
+
+// PodSelector selects the pod serving the model, empty fields are not matched
+type PodSelector struct {
+	ContainerName string
+	PodName       string
+	Namespace     string
+}
+
+// KeplerPodLabels are the label names matched by a PodSelector, empty if the counters lack the label
+type KeplerPodLabels struct {
+	Container string
+	Pod       string
+	Namespace string
+}
+
+// KeplerSchema describes how a Kepler release exposes the energy components
+type KeplerSchema struct {
+	Name string
+	// Counter of each component, components missing from the map are not exposed
+	Counters map[string]KeplerCounter
+	Labels   KeplerPodLabels
+	// Per-pod counters replacing the pod_* Counters when selecting by pod without container name,
+	// for releases whose container counters lack the pod name/namespace labels
+	PodCounters map[string]KeplerCounter
+	PodLabels   KeplerPodLabels
+	// Components summed up in place of a component whose counter matches no series,
+	// e.g. the platform of the CPUs without a psys RAPL zone
+	Fallbacks map[string][]string
+}
+
+// KeplerQuery is the query of one energy component
+type KeplerQuery struct {
+	Component string
+	Query     string
+}
+
+var keplerSchemas = map[string]KeplerSchema{
+	KeplerSchemaV08: {
+		Name: KeplerSchemaV08,
+		Counters: map[string]KeplerCounter{
+			NodePlatform: {Metric: "kepler_node_platform_joules_total"},
+			NodeGPU:      {Metric: "kepler_node_gpu_joules_total"},
+			NodePackage:  {Metric: "kepler_node_package_joules_total"},
+			NodeDRAM:     {Metric: "kepler_node_dram_joules_total"},
+			NodeOther:    {Metric: "kepler_node_other_joules_total"},
+			PodGPU:       {Metric: "kepler_container_gpu_joules_total"},
+			PodDRAM:      {Metric: "kepler_container_dram_joules_total"},
+			PodPackage:   {Metric: "kepler_container_package_joules_total"},
+			PodPlatform:  {Metric: "kepler_container_platform_joules_total"},
+			PodOther:     {Metric: "kepler_container_other_joules_total"},
+		},
+		Labels: KeplerPodLabels{
+			Container: "container_name",
+			Pod:       "pod_name",
+			Namespace: "container_namespace",
+		},
+	},
+	KeplerSchemaV010: {
+		Name: KeplerSchemaV010,
+		Counters: map[string]KeplerCounter{
+			NodePlatform: {Metric: "kepler_node_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "psys"}}},
+			NodeGPU:      {Metric: "kepler_node_gpu_joules_total"},
+			NodePackage:  {Metric: "kepler_node_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "package"}}},
+			NodeDRAM:     {Metric: "kepler_node_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "dram"}}},
+			PodGPU:       {Metric: "kepler_container_gpu_joules_total"},
+			PodDRAM:      {Metric: "kepler_container_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "dram"}}},
+			PodPackage:   {Metric: "kepler_container_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "package"}}},
+			PodPlatform:  {Metric: "kepler_container_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "psys"}}},
+		},
+		PodCounters: map[string]KeplerCounter{
+			PodGPU:      {Metric: "kepler_pod_gpu_joules_total"},
+			PodDRAM:     {Metric: "kepler_pod_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "dram"}}},
+			PodPackage:  {Metric: "kepler_pod_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "package"}}},
+			PodPlatform: {Metric: "kepler_pod_cpu_joules_total", Matchers: []Matcher{{Name: "zone", Value: "psys"}}},
+		},
+		Labels: KeplerPodLabels{
+			Container: "container_name",
+		},
+		PodLabels: KeplerPodLabels{
+			Pod:       "pod_name",
+			Namespace: "pod_namespace",
+		},
+		// Most x86 servers expose no psys zone, approximate the platform as v0.8 does
+		Fallbacks: map[string][]string{
+			NodePlatform: {NodePackage, NodeDRAM},
+			PodPlatform:  {PodPackage, PodDRAM},
+		},
+	},
+}
+
+// DetectKeplerSchema returns the Kepler schema with the given name, or probes the metric names
+// in Prometheus to pick it when name is auto or empty.
+// Counters whose metric is not found in Prometheus are removed from the returned schema,
+// a named schema is returned as is if the probe fails.
+func DetectKeplerSchema(ctx context.Context, name string) (KeplerSchema, error) {
+	if apiClient == nil {
+		return KeplerSchema{}, fmt.Errorf("prometheus API client is not initialized")
+	}
+
+	auto := name == "" || name == KeplerSchemaAuto
+	var values model.LabelValues
+	err := withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		values, _, err = apiClient.LabelValues(ctx, "__name__", []string{`{__name__=~"kepler_.+"}`}, time.Time{}, time.Time{})
+		return err
+	})
+	if err != nil {
+		if auto {
+			return KeplerSchema{}, fmt.Errorf("listing Kepler metrics: %v", err)
+		}
+		// The schema is known, keep all its counters
+		schema, ok := keplerSchemas[name]
+		if !ok {
+			return KeplerSchema{}, fmt.Errorf("unknown Kepler schema: %s", name)
+		}
+		return schema, nil
+	}
+	metrics := make(map[string]bool)
+	for _, v := range values {
+		metrics[string(v)] = true
+	}
+
+	if auto {
+		switch {
+		case metrics["kepler_node_cpu_joules_total"]:
+			name = KeplerSchemaV010
+		case metrics["kepler_node_platform_joules_total"] || metrics["kepler_node_package_joules_total"]:
+			name = KeplerSchemaV08
+		default:
+			return KeplerSchema{}, fmt.Errorf("no known Kepler energy metric found in Prometheus")
+		}
+	}
+	schema, ok := keplerSchemas[name]
+	if !ok {
+		return KeplerSchema{}, fmt.Errorf("unknown Kepler schema: %s", name)
+	}
+
+	prune := func(counters map[string]KeplerCounter) map[string]KeplerCounter {
+		exposed := make(map[string]KeplerCounter)
+		for component, counter := range counters {
+			if metrics[counter.Metric] {
+				exposed[component] = counter
+			}
+		}
+		return exposed
+	}
+	schema.Counters = prune(schema.Counters)
+	schema.PodCounters = prune(schema.PodCounters)
+	return schema, nil
+}
+
+// Queries returns the query of each exposed component, in the order of KeplerComponents.
+// nodeMatchers apply to every counter (e.g. instance), pod selects the serving pod on pod counters.
+func (ks KeplerSchema) Queries(nodeMatchers []Matcher, pod PodSelector) ([]KeplerQuery, error) {
+	counters, labels := ks.Counters, ks.Labels
+	byPod := pod.PodName != "" || pod.Namespace != ""
+	if byPod && pod.ContainerName == "" && (labels.Pod == "" || labels.Namespace == "") && len(ks.PodCounters) > 0 {
+		counters = make(map[string]KeplerCounter)
+		for component, counter := range ks.Counters {
+			if !strings.HasPrefix(component, "pod_") {
+				counters[component] = counter
+			}
+		}
+		for component, counter := range ks.PodCounters {
+			counters[component] = counter
+		}
+		labels = ks.PodLabels
+	}
+
+	var podMatchers []Matcher
+	for _, sel := range []struct{ label, value, field string }{
+		{labels.Container, pod.ContainerName, "container name"},
+		{labels.Pod, pod.PodName, "pod name"},
+		{labels.Namespace, pod.Namespace, "namespace"},
+	} {
+		if sel.value == "" {
+			continue
+		}
+		if sel.label == "" {
+			return nil, fmt.Errorf("kepler schema %s can't select the pod by %s along with the other selectors", ks.Name, sel.field)
+		}
+		podMatchers = append(podMatchers, Matcher{Name: sel.label, Value: sel.value})
+	}
+
+	var queries []KeplerQuery
+	for _, component := range KeplerComponents {
+		counter, ok := counters[component]
+		if !ok {
+			continue
+		}
+		matchers := append(append([]Matcher{}, counter.Matchers...), nodeMatchers...)
+		if strings.HasPrefix(component, "pod_") {
+			matchers = append(matchers, podMatchers...)
+		}
+		q, err := Selector(counter.Metric, matchers)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, KeplerQuery{Component: component, Query: q})
+	}
+	return queries, nil
+}
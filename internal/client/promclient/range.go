@@ -0,0 +1,204 @@
+package promclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// SeriesResult holds the raw samples of a single series returned by a range selector
+type SeriesResult struct {
+	Name    string             // Query name
+	Metric  model.Metric       // Metric labels
+	Samples []model.SamplePair // Raw samples in ascending time order
+}
+
+// SeriesResponse holds the full response for a range selector query
+type SeriesResponse struct {
+	Results  []SeriesResult // List of series for the query
+	Warnings []string       // Any warnings from the query
+	Error    error          // Any error from the query
+}
+
+// QuerySamples fetches the raw samples of the series selected by name within [start, end].
+// It evaluates name[end-start] at end, so the scrape timestamps are preserved
+// instead of being quantized to a query_range step.
+func QuerySamples(ctx context.Context, name string, start, end time.Time) SeriesResponse {
+	if apiClient == nil {
+		return SeriesResponse{Error: fmt.Errorf("prometheus API client is not initialized")}
+	}
+	if !end.After(start) {
+		return SeriesResponse{Error: fmt.Errorf("invalid window for %s: end %v is not after start %v", name, end, start)}
+	}
+
+	// PromQL durations must be integers, round the window up to the next millisecond
+	window := (end.Sub(start) + time.Millisecond - 1) / time.Millisecond
+	query := fmt.Sprintf("%s[%dms]", name, window)
+	var result model.Value
+	var warnings v1.Warnings
+	err := withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		result, warnings, err = apiClient.Query(ctx, query, end, v1.WithTimeout(retryOpts.timeout))
+		return err
+	})
+	if err != nil {
+		return SeriesResponse{Error: fmt.Errorf("querying Prometheus for %s: %v", query, err)}
+	}
+
+	response := SeriesResponse{
+		Warnings: warnings,
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		// Range selectors always evaluate to a matrix, anything else is unexpected
+		return response
+	}
+	for _, stream := range matrix {
+		response.Results = append(response.Results, SeriesResult{
+			Name:    name,
+			Metric:  stream.Metric,
+			Samples: stream.Values,
+		})
+	}
+	return response
+}
+
+// Within returns the samples of the response within (start, end], as QuerySamples over that range would.
+// Series without any sample in the range are dropped, so are the warnings.
+func (sr SeriesResponse) Within(start, end time.Time) SeriesResponse {
+	from, to := model.TimeFromUnixNano(start.UnixNano()), model.TimeFromUnixNano(end.UnixNano())
+	response := SeriesResponse{Error: sr.Error}
+	for _, res := range sr.Results {
+		i := sort.Search(len(res.Samples), func(i int) bool { return res.Samples[i].Timestamp > from })
+		j := sort.Search(len(res.Samples), func(j int) bool { return res.Samples[j].Timestamp > to })
+		if i == j {
+			continue
+		}
+		response.Results = append(response.Results, SeriesResult{
+			Name:    res.Name,
+			Metric:  res.Metric,
+			Samples: res.Samples[i:j],
+		})
+	}
+	return response
+}
+
+// SumIncrease sums the counter increase over [start, end] of every series,
+// NaN if the increase of any series is unknown
+func SumIncrease(results []SeriesResult, start, end time.Time) float64 {
+	var sum float64
+	for _, res := range results {
+		sum += IncreaseOverWindow(res.Samples, start, end)
+	}
+	return sum
+}
+
+// IncreaseOverWindow returns how much a counter grew between start and end.
+// Like PromQL increase(), a decreasing value is treated as a counter reset to zero.
+// Unlike increase(), the counter is linearly interpolated at the exact window edges
+// from the samples surrounding them, so short windows are not quantized to the scrape interval.
+// Edges outside the sampled range are extrapolated by at most half an average sample interval.
+// The increase is NaN with fewer than 2 samples, as the counter cannot be interpolated.
+func IncreaseOverWindow(samples []model.SamplePair, start, end time.Time) float64 {
+	if len(samples) < 2 {
+		return math.NaN()
+	}
+	if !end.After(start) {
+		return 0
+	}
+
+	// Accumulate the reset-corrected counter value at each sample
+	times := make([]float64, len(samples))
+	values := make([]float64, len(samples))
+	times[0] = float64(samples[0].Timestamp) / 1e3
+	values[0] = float64(samples[0].Value)
+	for i := 1; i < len(samples); i++ {
+		times[i] = float64(samples[i].Timestamp) / 1e3
+		cur, prev := float64(samples[i].Value), float64(samples[i-1].Value)
+		if cur >= prev {
+			values[i] = values[i-1] + cur - prev
+		} else {
+			// Counter reset, the new value is all the increase since the restart
+			values[i] = values[i-1] + cur
+		}
+	}
+
+	maxExtrapolation := (times[len(times)-1] - times[0]) / float64(len(times)-1) / 2
+	startSec := float64(start.UnixNano()) / 1e9
+	endSec := float64(end.UnixNano()) / 1e9
+	increase := counterAt(times, values, endSec, maxExtrapolation) - counterAt(times, values, startSec, maxExtrapolation)
+	return math.Max(increase, 0)
+}
+
+// counterAt interpolates the accumulated counter value at t (unit: seconds)
+func counterAt(times, values []float64, t, maxExtrapolation float64) float64 {
+	last := len(times) - 1
+	// Find the interval [times[i-1], times[i]] containing t, or the nearest one to extrapolate from
+	i := 1
+	for i < last && times[i] < t {
+		i++
+	}
+	switch {
+	case t < times[0]:
+		t = math.Max(t, times[0]-maxExtrapolation)
+	case t > times[last]:
+		t = math.Min(t, times[last]+maxExtrapolation)
+	}
+	dt := times[i] - times[i-1]
+	if dt <= 0 {
+		return values[i]
+	}
+	return values[i-1] + (values[i]-values[i-1])*(t-times[i-1])/dt
+}
+
+// RateQuery returns the query of the summed per-second rate of the counters selected by selector, e.g. watts from joules
+func RateQuery(selector string, window time.Duration) string {
+	return fmt.Sprintf("sum(rate(%s[%dms]))", selector, window.Milliseconds())
+}
+
+// QueryRangeSum evaluates query at every step within [start, end] and sums the resulting series at each timestamp.
+// The response holds a single series named query, without any sample when no series matched.
+func QueryRangeSum(ctx context.Context, query string, start, end time.Time, step time.Duration) SeriesResponse {
+	if apiClient == nil {
+		return SeriesResponse{Error: fmt.Errorf("prometheus API client is not initialized")}
+	}
+	if !end.After(start) || step <= 0 {
+		return SeriesResponse{Error: fmt.Errorf("invalid range for %s: [%v, %v] step %v", query, start, end, step)}
+	}
+
+	var result model.Value
+	var warnings v1.Warnings
+	err := withRetry(ctx, func(ctx context.Context) error {
+		var err error
+		result, warnings, err = apiClient.QueryRange(ctx, query, v1.Range{Start: start, End: end, Step: step}, v1.WithTimeout(retryOpts.timeout))
+		return err
+	})
+	if err != nil {
+		return SeriesResponse{Error: fmt.Errorf("querying Prometheus range for %s: %v", query, err)}
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return SeriesResponse{Warnings: warnings, Error: fmt.Errorf("unexpected result type %s for range query %s", result.Type(), query)}
+	}
+
+	sums := make(map[model.Time]model.SampleValue)
+	for _, stream := range matrix {
+		for _, sp := range stream.Values {
+			sums[sp.Timestamp] += sp.Value
+		}
+	}
+	samples := make([]model.SamplePair, 0, len(sums))
+	for ts, v := range sums {
+		samples = append(samples, model.SamplePair{Timestamp: ts, Value: v})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+	return SeriesResponse{
+		Results:  []SeriesResult{{Name: query, Samples: samples}},
+		Warnings: warnings,
+	}
+}
@@ -0,0 +1,137 @@
+package promclient
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+var t0 = time.Unix(1_700_000_000, 0)
+
+// counter returns the samples of a counter from (seconds after t0, value) points
+func counter(points ...[2]float64) []model.SamplePair {
+	samples := make([]model.SamplePair, len(points))
+	for i, p := range points {
+		ts := t0.Add(time.Duration(p[0] * float64(time.Second)))
+		samples[i] = model.SamplePair{Timestamp: model.TimeFromUnixNano(ts.UnixNano()), Value: model.SampleValue(p[1])}
+	}
+	return samples
+}
+
+// at returns the time sec seconds after t0
+func at(sec float64) time.Time {
+	return t0.Add(time.Duration(sec * float64(time.Second)))
+}
+
+func TestIncreaseOverWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []model.SamplePair
+		start, end float64 // Seconds after t0
+		want       float64
+	}{
+		{
+			name:    "monotonic",
+			samples: counter([2]float64{0, 100}, [2]float64{10, 110}, [2]float64{20, 120}, [2]float64{30, 130}),
+			start:   10, end: 30,
+			want: 20,
+		},
+		{
+			name:    "reset in the window",
+			samples: counter([2]float64{0, 0}, [2]float64{10, 10}, [2]float64{20, 5}, [2]float64{30, 15}),
+			start:   0, end: 30,
+			want: 25,
+		},
+		{
+			name:    "edges interpolated",
+			samples: counter([2]float64{0, 0}, [2]float64{10, 10}, [2]float64{20, 40}),
+			start:   5, end: 15,
+			want: 20,
+		},
+		{
+			name:    "edges interpolated across a reset",
+			samples: counter([2]float64{0, 50}, [2]float64{10, 60}, [2]float64{20, 10}, [2]float64{30, 20}),
+			start:   5, end: 25,
+			want: 20,
+		},
+		{
+			// The average interval is 10s, the edges are extrapolated by 5s at most
+			name:    "extrapolation capped",
+			samples: counter([2]float64{10, 0}, [2]float64{20, 10}, [2]float64{30, 20}),
+			start:   0, end: 40,
+			want: 30,
+		},
+		{
+			name:    "extrapolation within the cap",
+			samples: counter([2]float64{10, 0}, [2]float64{20, 10}, [2]float64{30, 20}),
+			start:   8, end: 33,
+			want: 25,
+		},
+		{
+			name:    "empty window",
+			samples: counter([2]float64{0, 0}, [2]float64{10, 10}),
+			start:   5, end: 5,
+			want: 0,
+		},
+		{
+			name:    "single sample",
+			samples: counter([2]float64{10, 10}),
+			start:   0, end: 20,
+			want: math.NaN(),
+		},
+		{
+			name:  "no sample",
+			start: 0, end: 20,
+			want: math.NaN(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IncreaseOverWindow(tt.samples, at(tt.start), at(tt.end))
+			if math.IsNaN(got) != math.IsNaN(tt.want) || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("IncreaseOverWindow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterAt(t *testing.T) {
+	times := []float64{10, 20, 30}
+	values := []float64{0, 10, 40}
+	tests := []struct {
+		t    float64
+		want float64
+	}{
+		{10, 0},
+		{15, 5},
+		{20, 10},
+		{25, 25},
+		{30, 40},
+		{32, 46},
+		{40, 55}, // Capped at 35
+		{7, -3},
+		{0, -5}, // Capped at 5
+	}
+	for _, tt := range tests {
+		if got := counterAt(times, values, tt.t, 5); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("counterAt(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestSumIncrease(t *testing.T) {
+	series := []SeriesResult{
+		{Samples: counter([2]float64{0, 0}, [2]float64{10, 10}, [2]float64{20, 20})},
+		{Samples: counter([2]float64{0, 0}, [2]float64{10, 30}, [2]float64{20, 60})},
+	}
+	if got := SumIncrease(series, at(0), at(20)); math.Abs(got-80) > 1e-9 {
+		t.Errorf("SumIncrease = %v, want 80", got)
+	}
+	// A series too short to compute its increase makes the sum unknown
+	series = append(series, SeriesResult{Samples: counter([2]float64{10, 5})})
+	if got := SumIncrease(series, at(0), at(20)); !math.IsNaN(got) {
+		t.Errorf("SumIncrease with a single-sample series = %v, want NaN", got)
+	}
+}
@@ -0,0 +1,60 @@
+package promclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// retryOpts sets how transient query failures are retried and how long each attempt may take, see ClientOptions
+var retryOpts = struct {
+	maxRetries int
+	backoff    time.Duration
+	timeout    time.Duration
+}{maxRetries: 3, backoff: 500 * time.Millisecond, timeout: 30 * time.Second}
+
+// isTransient tells whether a failed query may succeed when retried
+func isTransient(err error) bool {
+	var apiErr *v1.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Type {
+		case v1.ErrTimeout, v1.ErrServer, v1.ErrBadResponse:
+			return true
+		case v1.ErrClient:
+			// Rate limited by the server or a proxy in front of it
+			return strings.Contains(apiErr.Msg, "429")
+		default:
+			// Invalid query or canceled by the server
+			return false
+		}
+	}
+	// Network failures and attempts timing out
+	return true
+}
+
+// withRetry calls query with a per-attempt timeout, retrying transient failures with an exponential
+// backoff until the retries are exhausted or ctx is done
+func withRetry(ctx context.Context, query func(ctx context.Context) error) error {
+	backoff := retryOpts.backoff
+	for attempt := 0; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, retryOpts.timeout)
+		err := query(actx)
+		cancel()
+		if err == nil || attempt >= retryOpts.maxRetries || ctx.Err() != nil || !isTransient(err) {
+			return err
+		}
+
+		// Jitter spreads the retries of concurrent queries
+		wait := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
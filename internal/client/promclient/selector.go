@@ -0,0 +1,45 @@
+package promclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Matcher is a PromQL label matcher
+type Matcher struct {
+	Name  string
+	Type  string // One of =, !=, =~ and !~, defaults to =
+	Value string
+}
+
+// Selector builds the PromQL series selector of metric with the given matchers.
+// Label names are validated and values are quoted, so arbitrary values can't alter the query.
+func Selector(metric string, matchers []Matcher) (string, error) {
+	if !model.IsValidLegacyMetricName(metric) {
+		return "", fmt.Errorf("invalid metric name: %q", metric)
+	}
+	if len(matchers) == 0 {
+		return metric, nil
+	}
+
+	parts := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		if !model.LabelName(m.Name).IsValidLegacy() {
+			return "", fmt.Errorf("invalid label name: %q", m.Name)
+		}
+		op := m.Type
+		switch op {
+		case "":
+			op = "="
+		case "=", "!=", "=~", "!~":
+		default:
+			return "", fmt.Errorf("invalid matcher type %q for label %s", m.Type, m.Name)
+		}
+		// PromQL string literals follow Go's escaping rules
+		parts = append(parts, m.Name+op+strconv.Quote(m.Value))
+	}
+	return metric + "{" + strings.Join(parts, ",") + "}", nil
+}
@@ -0,0 +1,124 @@
+package promclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+)
+
+// ClientOptions sets the authentication, TLS and headers of the requests sent to Prometheus.
+// Token and password files are read on every request so rotated secrets are picked up.
+type ClientOptions struct {
+	BearerToken     string
+	BearerTokenFile string
+
+	Username     string
+	Password     string
+	PasswordFile string
+
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+
+	// Extra headers, e.g. X-Scope-OrgID selecting the tenant of Mimir, Cortex or Thanos
+	Headers map[string]string
+
+	// Retries of the queries failing with a transient error, the backoff doubles after each retry
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// Timeout of each attempt of a query, both on the server and the client side
+	QueryTimeout time.Duration
+}
+
+// authRoundTripper adds the headers and the credentials of the options to every request
+type authRoundTripper struct {
+	opts ClientOptions
+	next http.RoundTripper
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	switch {
+	case rt.opts.BearerToken != "" || rt.opts.BearerTokenFile != "":
+		token, err := secret(rt.opts.BearerToken, rt.opts.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading bearer token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case rt.opts.Username != "":
+		password, err := secret(rt.opts.Password, rt.opts.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading basic auth password: %v", err)
+		}
+		req.SetBasicAuth(rt.opts.Username, password)
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// secret returns value, or the trimmed content of file when set
+func secret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// newRoundTripper builds the transport of the Prometheus client from the options
+func newRoundTripper(opts ClientOptions) (http.RoundTripper, error) {
+	if opts.BearerToken != "" && opts.BearerTokenFile != "" {
+		return nil, fmt.Errorf("bearer_token and bearer_token_file are mutually exclusive")
+	}
+	if (opts.BearerToken != "" || opts.BearerTokenFile != "") && opts.Username != "" {
+		return nil, fmt.Errorf("bearer token and basic auth are mutually exclusive")
+	}
+	if opts.Password != "" && opts.PasswordFile != "" {
+		return nil, fmt.Errorf("password and password_file are mutually exclusive")
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := api.DefaultRoundTripper.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &authRoundTripper{opts: opts, next: transport}, nil
+}
@@ -0,0 +1,231 @@
+package promclient
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tlsServer starts a TLS server and writes its certificate into a CA file
+func tlsServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+// get sends a request to url through the transport built from opts
+func get(opts ClientOptions, url string) error {
+	rt, err := newRoundTripper(opts)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: rt}).Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestRoundTripperAuth(t *testing.T) {
+	var got http.Header
+	srv, caFile := tlsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	})
+	secretFile := func(content string) string {
+		path := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name  string
+		opts  ClientOptions
+		want  map[string]string
+		basic [2]string // Expected user and password, if any
+	}{
+		{
+			name: "bearer token",
+			opts: ClientOptions{BearerToken: "s3cr3t"},
+			want: map[string]string{"Authorization": "Bearer s3cr3t"},
+		},
+		{
+			name: "bearer token file",
+			opts: ClientOptions{BearerTokenFile: secretFile("from-file")},
+			want: map[string]string{"Authorization": "Bearer from-file"},
+		},
+		{
+			name:  "basic auth",
+			opts:  ClientOptions{Username: "admin", Password: "pass"},
+			basic: [2]string{"admin", "pass"},
+		},
+		{
+			name:  "basic auth password file",
+			opts:  ClientOptions{Username: "admin", PasswordFile: secretFile("pass-from-file")},
+			basic: [2]string{"admin", "pass-from-file"},
+		},
+		{
+			name: "headers",
+			opts: ClientOptions{BearerToken: "t", Headers: map[string]string{"X-Scope-OrgID": "tenant-1"}},
+			want: map[string]string{"X-Scope-OrgID": "tenant-1", "Authorization": "Bearer t"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.CAFile = caFile
+			got = nil
+			if err := get(tt.opts, srv.URL); err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			for name, value := range tt.want {
+				if v := got.Get(name); v != value {
+					t.Errorf("header %s = %q, want %q", name, v, value)
+				}
+			}
+			if tt.basic[0] != "" {
+				req := &http.Request{Header: got}
+				user, password, ok := req.BasicAuth()
+				if !ok || user != tt.basic[0] || password != tt.basic[1] {
+					t.Errorf("basic auth = %q, %q, %v, want %q, %q", user, password, ok, tt.basic[0], tt.basic[1])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripperRereadsTokenFile(t *testing.T) {
+	var auth []string
+	srv, caFile := tlsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	})
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	rt, err := newRoundTripper(ClientOptions{BearerTokenFile: tokenFile, CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rt}
+	// The token is rotated between the requests
+	for _, token := range []string{"first", "second"} {
+		if err := os.WriteFile(tokenFile, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if len(auth) != 2 || auth[0] != "Bearer first" || auth[1] != "Bearer second" {
+		t.Errorf("Authorization headers = %q, want the rotated tokens", auth)
+	}
+
+	// A missing token file fails the request instead of sending it unauthenticated
+	os.Remove(tokenFile)
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Error("request with a missing token file succeeded, want an error")
+	}
+}
+
+func TestRoundTripperTLS(t *testing.T) {
+	srv, caFile := tlsServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	// The certificate of httptest servers is valid for 127.0.0.1 and example.com
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr bool
+	}{
+		{name: "CA", opts: ClientOptions{CAFile: caFile}},
+		{name: "unknown CA", opts: ClientOptions{}, wantErr: true},
+		{name: "server name", opts: ClientOptions{CAFile: caFile, ServerName: "example.com"}},
+		{name: "wrong server name", opts: ClientOptions{CAFile: caFile, ServerName: "prometheus.invalid"}, wantErr: true},
+		{name: "insecure skip verify", opts: ClientOptions{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := get(tt.opts, srv.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("request error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRoundTripperInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ClientOptions
+	}{
+		{"token and token file", ClientOptions{BearerToken: "t", BearerTokenFile: "f"}},
+		{"token and basic auth", ClientOptions{BearerToken: "t", Username: "u"}},
+		{"password and password file", ClientOptions{Username: "u", Password: "p", PasswordFile: "f"}},
+		{"cert without key", ClientOptions{CertFile: "cert.pem"}},
+		{"missing CA file", ClientOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newRoundTripper(tt.opts); err == nil {
+				t.Error("newRoundTripper succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestQueryRetries(t *testing.T) {
+	saved := retryOpts
+	t.Cleanup(func() { retryOpts = saved })
+
+	tests := []struct {
+		name         string
+		status       int
+		failures     int // Requests failing with status before a success
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{name: "unavailable", status: http.StatusServiceUnavailable, failures: 2, wantAttempts: 3},
+		{name: "rate limited", status: http.StatusTooManyRequests, failures: 2, wantAttempts: 3},
+		{name: "retries exhausted", status: http.StatusServiceUnavailable, failures: 5, wantErr: true, wantAttempts: 3},
+		{name: "bad request", status: http.StatusBadRequest, failures: 1, wantErr: true, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv, caFile := tlsServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if int(attempts.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"failed"}`))
+					return
+				}
+				w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+			})
+			if err := Init(srv.URL, ClientOptions{CAFile: caFile, MaxRetries: 2, RetryBackoff: time.Millisecond}); err != nil {
+				t.Fatal(err)
+			}
+
+			end := time.Now()
+			resp := QuerySamples(context.Background(), "up", end.Add(-time.Minute), end)
+			if (resp.Error != nil) != tt.wantErr {
+				t.Errorf("query error = %v, want error %v", resp.Error, tt.wantErr)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/explorerray/itpe-report/internal/exporter/dataset"
+)
+
+// Status of a metric in the candidate compared to the baseline
+type Status string
+
+const (
+	StatusOK        Status = "ok"
+	StatusImproved  Status = "improved"
+	StatusRegressed Status = "regressed"
+	StatusInfo      Status = "info"    // Metric without a better direction, never a regression
+	StatusMissing   Status = "missing" // Metric missing in one of the result sets
+)
+
+// Direction tells whether a higher value of a metric is better
+type Direction int
+
+const (
+	Neutral Direction = iota
+	HigherIsBetter
+	LowerIsBetter
+)
+
+// MetricDirection infers whether a higher value of a metric is better from its column name
+func MetricDirection(metric string) Direction {
+	switch {
+	case strings.HasSuffix(metric, "_stddev"), strings.HasSuffix(metric, "_ci95"):
+		// Spread over the repetitions
+		return Neutral
+	case strings.HasPrefix(metric, "grid_intensity"):
+		// Property of the grid, not of the deployment
+		return Neutral
+	case strings.HasPrefix(metric, "idle_"):
+		// Baseline of the node, not a result of the experiment
+		return Neutral
+	case strings.Contains(metric, "throughput"), strings.Contains(metric, "per_joule"):
+		return HigherIsBetter
+	case strings.HasSuffix(metric, "_ms"), strings.Contains(metric, "_ms_"),
+		strings.HasSuffix(metric, "_j"), strings.Contains(metric, "energy"),
+		strings.HasSuffix(metric, "_kwh"), strings.HasPrefix(metric, "carbon_g"), strings.HasPrefix(metric, "cost"):
+		return LowerIsBetter
+	default:
+		return Neutral
+	}
+}
+
+// Thresholds holds the relative change (unit: percent) tolerated before a metric is flagged
+type Thresholds struct {
+	Default   float64
+	PerMetric map[string]float64
+}
+
+func (t Thresholds) of(metric string) float64 {
+	if v, ok := t.PerMetric[metric]; ok {
+		return v
+	}
+	return t.Default
+}
+
+// MetricDelta is the change of one metric of an experiment
+type MetricDelta struct {
+	Metric    string
+	Baseline  float64
+	Candidate float64
+	DeltaPct  float64 // Relative change from the baseline (unit: percent)
+	Status    Status
+}
+
+// ExpDiff holds the metric changes of one experiment
+type ExpDiff struct {
+	Conf   []string // Values of the dataset.ConfColumns
+	Deltas []MetricDelta
+}
+
+// Result is the comparison of two result sets
+type Result struct {
+	Baseline    string
+	Candidate   string
+	ConfColumns []string
+	Experiments []ExpDiff
+	// Experiments found in a single result set
+	MissingInCandidate [][]string
+	NewInCandidate     [][]string
+	Regressions        int
+	Improvements       int
+	// Metrics known in the baseline and missing in the candidate
+	Lost int
+}
+
+// confKey returns the values identifying the experiment of row
+func confKey(row map[string]any, confColumns []string) []string {
+	key := make([]string, len(confColumns))
+	for i, c := range confColumns {
+		switch v := row[c].(type) {
+		case float64:
+			if math.IsNaN(v) {
+				key[i] = ""
+			} else {
+				key[i] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		case string:
+			key[i] = v
+		}
+	}
+	return key
+}
+
+// Compare matches the experiments of both result sets by their config and computes the change of each metric
+func Compare(baseline, candidate *dataset.Dataset, thresholds Thresholds) Result {
+	isConf := make(map[string]bool)
+	var confColumns []string
+	for _, c := range dataset.ConfColumns() {
+		isConf[c] = true
+		// Result sets exported by older releases lack the newer config columns, which are then not compared
+		if slices.Contains(baseline.Columns, c) && slices.Contains(candidate.Columns, c) {
+			confColumns = append(confColumns, c)
+		}
+	}
+
+	res := Result{
+		Baseline:    baseline.Path,
+		Candidate:   candidate.Path,
+		ConfColumns: confColumns,
+	}
+	candRows := make(map[string]map[string]any)
+	candKeys := make(map[string][]string)
+	for _, row := range candidate.Rows {
+		key := confKey(row, confColumns)
+		candRows[strings.Join(key, "\x00")] = row
+		candKeys[strings.Join(key, "\x00")] = key
+	}
+
+	matched := make(map[string]bool)
+	for _, baseRow := range baseline.Rows {
+		key := confKey(baseRow, confColumns)
+		id := strings.Join(key, "\x00")
+		candRow, ok := candRows[id]
+		if !ok {
+			res.MissingInCandidate = append(res.MissingInCandidate, key)
+			continue
+		}
+		matched[id] = true
+
+		ed := ExpDiff{Conf: key}
+		for _, metric := range baseline.Columns {
+			if isConf[metric] {
+				continue
+			}
+			bv, bok := baseRow[metric].(float64)
+			cv, cok := candRow[metric].(float64)
+			if !bok && !cok {
+				// Text column, e.g. the served model
+				continue
+			}
+			md := MetricDelta{Metric: metric, Baseline: math.NaN(), Candidate: math.NaN(), DeltaPct: math.NaN()}
+			if bok {
+				md.Baseline = bv
+			}
+			if cok {
+				md.Candidate = cv
+			}
+			md.Status = status(&md, MetricDirection(metric), thresholds.of(metric))
+			switch md.Status {
+			case StatusRegressed:
+				res.Regressions++
+			case StatusImproved:
+				res.Improvements++
+			case StatusMissing:
+				if !math.IsNaN(md.Baseline) {
+					res.Lost++
+				}
+			}
+			ed.Deltas = append(ed.Deltas, md)
+		}
+		res.Experiments = append(res.Experiments, ed)
+	}
+	for id, key := range candKeys {
+		if !matched[id] {
+			res.NewInCandidate = append(res.NewInCandidate, key)
+		}
+	}
+	sort.Slice(res.NewInCandidate, func(i, j int) bool {
+		return strings.Join(res.NewInCandidate[i], "\x00") < strings.Join(res.NewInCandidate[j], "\x00")
+	})
+	return res
+}
+
+// status computes the relative change of md and classifies it
+func status(md *MetricDelta, dir Direction, tolerancePct float64) Status {
+	if math.IsNaN(md.Baseline) || math.IsNaN(md.Candidate) {
+		if math.IsNaN(md.Baseline) && math.IsNaN(md.Candidate) {
+			return StatusOK
+		}
+		return StatusMissing
+	}
+
+	diff := md.Candidate - md.Baseline
+	switch {
+	case diff == 0:
+		md.DeltaPct = 0
+	case md.Baseline == 0:
+		md.DeltaPct = math.Copysign(math.Inf(1), diff)
+	default:
+		md.DeltaPct = diff / math.Abs(md.Baseline) * 100
+	}
+
+	if dir == Neutral {
+		return StatusInfo
+	}
+	if math.Abs(md.DeltaPct) <= tolerancePct {
+		return StatusOK
+	}
+	if (dir == HigherIsBetter) == (md.DeltaPct > 0) {
+		return StatusImproved
+	}
+	return StatusRegressed
+}
+
+// FormatDelta formats a relative change (unit: percent)
+func FormatDelta(pct float64) string {
+	if math.IsNaN(pct) {
+		return "N/A"
+	}
+	return fmt.Sprintf("%+.2f%%", pct)
+}
+
+// FormatValue formats a metric value
+func FormatValue(v float64) string {
+	if math.IsNaN(v) {
+		return "N/A"
+	}
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
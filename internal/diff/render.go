@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+//go:embed diff.html.tmpl
+var diffTmpl string
+
+// shown tells whether a metric change is rendered, unchanged metrics are only shown with all
+func shown(md MetricDelta, all bool) bool {
+	return all || md.Status == StatusRegressed || md.Status == StatusImproved || md.Status == StatusMissing
+}
+
+// RenderTable writes the comparison as a table, with only the flagged metrics unless all is set
+func RenderTable(res Result, all bool, w io.Writer) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	header := table.Row{}
+	for _, c := range res.ConfColumns {
+		header = append(header, c)
+	}
+	header = append(header, "Metric", "Baseline", "Candidate", "Delta", "Status")
+	t.AppendHeader(header)
+	// Right-align the values
+	n := len(res.ConfColumns)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: n + 2, Align: text.AlignRight},
+		{Number: n + 3, Align: text.AlignRight},
+		{Number: n + 4, Align: text.AlignRight},
+	})
+
+	for _, ed := range res.Experiments {
+		for _, md := range ed.Deltas {
+			if !shown(md, all) {
+				continue
+			}
+			row := table.Row{}
+			for _, v := range ed.Conf {
+				row = append(row, v)
+			}
+			row = append(row, md.Metric, FormatValue(md.Baseline), FormatValue(md.Candidate), FormatDelta(md.DeltaPct), strings.ToUpper(string(md.Status)))
+			t.AppendRow(row)
+		}
+	}
+
+	fmt.Fprintf(w, "Baseline:  %s\nCandidate: %s\n", res.Baseline, res.Candidate)
+	t.Render()
+	for _, key := range res.MissingInCandidate {
+		fmt.Fprintf(w, "Missing in candidate: %s\n", strings.Join(key, " "))
+	}
+	for _, key := range res.NewInCandidate {
+		fmt.Fprintf(w, "New in candidate: %s\n", strings.Join(key, " "))
+	}
+	fmt.Fprintf(w, "%d experiments compared, %d regressions, %d improvements, %d metrics lost\n",
+		len(res.Experiments), res.Regressions, res.Improvements, res.Lost)
+}
+
+// WriteHTML writes the full comparison as a self-contained HTML page
+func WriteHTML(res Result, path string) error {
+	tmpl, err := template.New("diff").Funcs(template.FuncMap{
+		"value": FormatValue,
+		"delta": FormatDelta,
+		"join":  strings.Join,
+	}).Parse(diffTmpl)
+	if err != nil {
+		return fmt.Errorf("parsing diff template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, res); err != nil {
+		return fmt.Errorf("rendering diff: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing diff %s: %v", path, err)
+	}
+	return nil
+}
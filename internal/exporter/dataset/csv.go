@@ -0,0 +1,39 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+)
+
+// WriteCSV writes the table as CSV with a header row
+func WriteCSV(t Table, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, v := range row {
+			record[i] = formatValue(v)
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("writing %s: %v", path, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return f.Close()
+}
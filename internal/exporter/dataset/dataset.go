@@ -0,0 +1,223 @@
+package dataset
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/explorerray/itpe-report/internal/input"
+)
+
+// Output file names in the artifacts directory
+const (
+	CSVFilename     = "results.csv"
+	JSONLFilename   = "results.jsonl"
+	ParquetFilename = "results.parquet"
+)
+
+// Column is a single column of the dataset
+type Column struct {
+	Name string
+	Kind reflect.Kind // String, Int, Float64 or Bool
+}
+
+// Table is the joined perf & energy dataset, one row per experiment.
+// Row values are string, int, float64 or bool according to the column kind.
+type Table struct {
+	Columns []Column
+	Rows    [][]any
+}
+
+// derivedColumns are computed from the metrics of an experiment
+var derivedColumns = []struct {
+	name  string
+	value func(m input.ExpMetrics) float64
+}{
+	{"energy_per_token_j", func(m input.ExpMetrics) float64 { return m.EnergyPerToken() }},
+	{"energy_per_request_j", func(m input.ExpMetrics) float64 {
+		return m.PowerM.NodePlatformJ / float64(m.PerfM.NumRequests)
+	}},
+	{"pod_energy_per_token_j", func(m input.ExpMetrics) float64 {
+		return m.PowerM.PodPlatformJ / float64(m.PerfM.TotalOutputTokens)
+	}},
+	{"output_tokens_per_joule", func(m input.ExpMetrics) float64 {
+		return float64(m.PerfM.TotalOutputTokens) / m.PowerM.NodePlatformJ
+	}},
+	{"net_energy_per_token_j", func(m input.ExpMetrics) float64 { return m.NetEnergyPerToken() }},
+	{"net_energy_per_request_j", func(m input.ExpMetrics) float64 {
+		return m.NetM.NodePlatformJ / float64(m.PerfM.NumRequests)
+	}},
+	{"carbon_g_per_request", input.ExpMetrics.CarbonPerRequest},
+	{"carbon_g_per_1m_tokens", input.ExpMetrics.CarbonPerMTokens},
+	{"cost_per_request", input.ExpMetrics.CostPerRequest},
+	{"cost_per_1m_tokens", input.ExpMetrics.CostPerMTokens},
+}
+
+// NewTable flattens the experiment config, the perf & energy metrics and the derived metrics of every experiment.
+// Columns are named after the json tags of the metric structs, nested structs are prefixed by their parent name.
+// When experiments were repeated, every numeric metric is followed by its _stddev and _ci95 over the repetitions.
+func NewTable(emp input.ExpMetricPair) Table {
+	withStats := false
+	for _, em := range emp {
+		if em.Repetitions() > 1 {
+			withStats = true
+		}
+	}
+
+	var t Table
+	for i, ec := range emp.SortedConfs() {
+		em := emp[ec]
+		var cols []Column
+		var row []any
+		flatten("", reflect.ValueOf(ec), &cols, &row)
+		cols = append(cols, Column{Name: "repetitions", Kind: reflect.Int}, Column{Name: "expected_repetitions", Kind: reflect.Int},
+			Column{Name: "partial", Kind: reflect.Bool})
+		row = append(row, em.Repetitions(), em.ExpectedRepetitions(), em.Partial)
+
+		mcols, mrow := metricColumns(em)
+		repRows := [][]any{mrow}
+		if len(em.Reps) > 0 {
+			repRows = repRows[:0]
+			for _, rep := range em.Reps {
+				_, r := metricColumns(rep)
+				repRows = append(repRows, r)
+			}
+		}
+		for k, col := range mcols {
+			cols = append(cols, col)
+			row = append(row, mrow[k])
+			if !withStats || (col.Kind != reflect.Float64 && col.Kind != reflect.Int) {
+				continue
+			}
+			values := make([]float64, len(repRows))
+			for j, r := range repRows {
+				values[j] = toFloat(r[k])
+			}
+			rs := input.NewRepStats(values)
+			cols = append(cols, Column{Name: col.Name + "_stddev", Kind: reflect.Float64}, Column{Name: col.Name + "_ci95", Kind: reflect.Float64})
+			row = append(row, rs.Stddev, rs.CI95)
+		}
+		if i == 0 {
+			t.Columns = cols
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// metricColumns flattens the perf & energy metrics and the derived metrics of an experiment
+func metricColumns(em input.ExpMetrics) ([]Column, []any) {
+	var cols []Column
+	var row []any
+	flatten("", reflect.ValueOf(em.PerfM), &cols, &row)
+	flatten("", reflect.ValueOf(em.PowerM), &cols, &row)
+	flatten("idle_", reflect.ValueOf(em.IdleM), &cols, &row)
+	flatten("net_", reflect.ValueOf(em.NetM), &cols, &row)
+	flatten("power_", reflect.ValueOf(em.PowerW), &cols, &row)
+	flatten("", reflect.ValueOf(em.Footprint), &cols, &row)
+	for _, dc := range derivedColumns {
+		v := dc.value(em)
+		if math.IsInf(v, 0) {
+			// Division by zero, the metric is not available
+			v = math.NaN()
+		}
+		cols = append(cols, Column{Name: dc.name, Kind: reflect.Float64})
+		row = append(row, v)
+	}
+	return cols, row
+}
+
+// toFloat converts a numeric row value to float64
+func toFloat(v any) float64 {
+	switch val := v.(type) {
+	case int:
+		return float64(val)
+	case float64:
+		return val
+	default:
+		return math.NaN()
+	}
+}
+
+// flatten appends the exported fields of the struct v as columns
+func flatten(prefix string, v reflect.Value, cols *[]Column, row *[]any) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		name = prefix + name
+
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Struct:
+			flatten(name+"_", fv, cols, row)
+		case reflect.String:
+			*cols = append(*cols, Column{Name: name, Kind: reflect.String})
+			*row = append(*row, fv.String())
+		case reflect.Int, reflect.Int64:
+			*cols = append(*cols, Column{Name: name, Kind: reflect.Int})
+			*row = append(*row, int(fv.Int()))
+		case reflect.Float64:
+			f := fv.Float()
+			if math.IsInf(f, 0) {
+				// e.g. a rate over an empty window, the metric is not available
+				f = math.NaN()
+			}
+			*cols = append(*cols, Column{Name: name, Kind: reflect.Float64})
+			*row = append(*row, f)
+		case reflect.Bool:
+			*cols = append(*cols, Column{Name: name, Kind: reflect.Bool})
+			*row = append(*row, fv.Bool())
+		}
+	}
+}
+
+// formatValue formats a row value as text, missing (NaN) values are empty
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case int:
+		return strconv.Itoa(val)
+	case float64:
+		if math.IsNaN(val) {
+			return ""
+		}
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return ""
+	}
+}
+
+// Exporter writes the dataset in a single format into the artifacts directory
+type Exporter struct {
+	name  string
+	path  string
+	write func(Table, string) error
+}
+
+// NewExporter creates an exporter writing the dataset with write into dir/filename
+func NewExporter(name, dir, filename string, write func(Table, string) error) *Exporter {
+	return &Exporter{name: name, path: filepath.Join(dir, filename), write: write}
+}
+
+func (e *Exporter) Name() string {
+	return e.name
+}
+
+func (e *Exporter) Export(emp input.ExpMetricPair) error {
+	return e.write(NewTable(emp), e.path)
+}
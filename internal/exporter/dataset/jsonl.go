@@ -0,0 +1,46 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// WriteJSONL writes the table as JSON Lines, one flat object per row with the columns in order.
+// Missing (NaN) and infinite values are written as null.
+func WriteJSONL(t Table, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, row := range t.Rows {
+		w.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			key, _ := json.Marshal(t.Columns[i].Name)
+			w.Write(key)
+			w.WriteByte(':')
+			if fv, ok := v.(float64); ok && (math.IsNaN(fv) || math.IsInf(fv, 0)) {
+				w.WriteString("null")
+				continue
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("encoding %s of %s: %v", t.Columns[i].Name, path, err)
+			}
+			w.Write(value)
+		}
+		w.WriteString("}\n")
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return f.Close()
+}
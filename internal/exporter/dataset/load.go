@@ -0,0 +1,145 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/explorerray/itpe-report/internal/input"
+)
+
+// Dataset is an exported result set loaded back from disk.
+// Row values are either string or float64, missing values are NaN.
+type Dataset struct {
+	Path    string
+	Columns []string
+	Rows    []map[string]any
+}
+
+// ConfColumns returns the columns identifying an experiment, i.e. the flattened GenAIPerfExpConf
+func ConfColumns() []string {
+	var cols []Column
+	var row []any
+	flatten("", reflect.ValueOf(input.GenAIPerfExpConf{}), &cols, &row)
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Load reads a dataset exported as JSON Lines or CSV.
+// If path is a directory, its results.jsonl is loaded, or results.csv if there is none.
+func Load(path string) (*Dataset, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		dir := path
+		path = filepath.Join(dir, JSONLFilename)
+		if _, err := os.Stat(path); err != nil {
+			path = filepath.Join(dir, CSVFilename)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening dataset: %v", err)
+	}
+	defer f.Close()
+
+	switch filepath.Ext(path) {
+	case ".jsonl":
+		return loadJSONL(path, f)
+	case ".csv":
+		return loadCSV(path, f)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format %s, expecting .jsonl or .csv", path)
+	}
+}
+
+func loadJSONL(path string, r io.Reader) (*Dataset, error) {
+	ds := &Dataset{Path: path}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		// Decode in order to keep the column order of the file
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.UseNumber()
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("parsing %s line %d: expecting a JSON object", path, line)
+		}
+		row := make(map[string]any)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s line %d: %v", path, line, err)
+			}
+			key := tok.(string)
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("parsing %s line %d: %v", path, line, err)
+			}
+			switch v := value.(type) {
+			case json.Number:
+				fv, _ := v.Float64()
+				row[key] = fv
+			case nil:
+				row[key] = math.NaN()
+			case bool:
+				row[key] = strconv.FormatBool(v)
+			case string:
+				row[key] = v
+			default:
+				row[key] = fmt.Sprint(v)
+			}
+			if !seen[key] {
+				seen[key] = true
+				ds.Columns = append(ds.Columns, key)
+			}
+		}
+		ds.Rows = append(ds.Rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return ds, nil
+}
+
+func loadCSV(path string, r io.Reader) (*Dataset, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("reading %s: missing header", path)
+	}
+
+	ds := &Dataset{Path: path, Columns: records[0]}
+	for _, record := range records[1:] {
+		row := make(map[string]any)
+		for i, value := range record {
+			if i >= len(ds.Columns) {
+				break
+			}
+			if value == "" {
+				row[ds.Columns[i]] = math.NaN()
+			} else if fv, err := strconv.ParseFloat(value, 64); err == nil {
+				row[ds.Columns[i]] = fv
+			} else {
+				row[ds.Columns[i]] = value
+			}
+		}
+		ds.Rows = append(ds.Rows, row)
+	}
+	return ds, nil
+}
@@ -0,0 +1,70 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// WriteParquet writes the table as a Parquet file.
+// Float columns are optional so that missing (NaN) values are written as null.
+func WriteParquet(t Table, path string) error {
+	// Build a struct type mirroring the columns, parquet-go derives the schema from it
+	fields := make([]reflect.StructField, len(t.Columns))
+	for i, c := range t.Columns {
+		var typ reflect.Type
+		tag := c.Name
+		switch c.Kind {
+		case reflect.String:
+			typ = reflect.TypeOf("")
+		case reflect.Int:
+			typ = reflect.TypeOf(int64(0))
+		case reflect.Bool:
+			typ = reflect.TypeOf(false)
+		default:
+			typ = reflect.TypeOf((*float64)(nil))
+			tag += ",optional"
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"%s"`, tag)),
+		}
+	}
+	rowType := reflect.StructOf(fields)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	w := parquet.NewWriter(f, parquet.SchemaOf(reflect.New(rowType).Interface()))
+	for _, row := range t.Rows {
+		rv := reflect.New(rowType).Elem()
+		for i, v := range row {
+			switch val := v.(type) {
+			case string:
+				rv.Field(i).SetString(val)
+			case int:
+				rv.Field(i).SetInt(int64(val))
+			case bool:
+				rv.Field(i).SetBool(val)
+			case float64:
+				if !math.IsNaN(val) {
+					rv.Field(i).Set(reflect.ValueOf(&val))
+				}
+			}
+		}
+		if err := w.Write(rv.Addr().Interface()); err != nil {
+			return fmt.Errorf("writing %s: %v", path, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return f.Close()
+}
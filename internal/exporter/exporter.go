@@ -1 +1,56 @@
 package exporter
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/explorerray/itpe-report/config"
+	"github.com/explorerray/itpe-report/internal/exporter/dataset"
+	"github.com/explorerray/itpe-report/internal/exporter/html"
+	"github.com/explorerray/itpe-report/internal/exporter/plot"
+	"github.com/explorerray/itpe-report/internal/exporter/stdout"
+	"github.com/explorerray/itpe-report/internal/input"
+)
+
+// Exporter writes the experiment metrics to one kind of output
+type Exporter interface {
+	// Name is the output name selected with --output
+	Name() string
+	Export(emp input.ExpMetricPair) error
+}
+
+// Supported outputs
+const (
+	OutputPlots   = "plots"
+	OutputTable   = "table"
+	OutputHTML    = "html"
+	OutputCSV     = "csv"
+	OutputJSONL   = "jsonl"
+	OutputParquet = "parquet"
+)
+
+// New creates the exporters of the given outputs, in the same order
+func New(outputs []string, c config.Config, logger *slog.Logger) ([]Exporter, error) {
+	var exporters []Exporter
+	for _, output := range outputs {
+		var e Exporter
+		switch output {
+		case OutputPlots:
+			e = plot.NewExporter(c, logger)
+		case OutputTable:
+			e = stdout.NewExporter()
+		case OutputHTML:
+			e = html.NewExporter(c, logger)
+		case OutputCSV:
+			e = dataset.NewExporter(OutputCSV, c.ReportConf.ArtfDir, dataset.CSVFilename, dataset.WriteCSV)
+		case OutputJSONL:
+			e = dataset.NewExporter(OutputJSONL, c.ReportConf.ArtfDir, dataset.JSONLFilename, dataset.WriteJSONL)
+		case OutputParquet:
+			e = dataset.NewExporter(OutputParquet, c.ReportConf.ArtfDir, dataset.ParquetFilename, dataset.WriteParquet)
+		default:
+			return nil, fmt.Errorf("unknown output: %s", output)
+		}
+		exporters = append(exporters, e)
+	}
+	return exporters, nil
+}
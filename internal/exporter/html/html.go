@@ -0,0 +1,301 @@
+package html
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+	"github.com/explorerray/itpe-report/internal/exporter/plot"
+	"github.com/explorerray/itpe-report/internal/input"
+)
+
+//go:embed report.html.tmpl
+var reportTmpl string
+
+// ReportFilename is the name of the generated report in the artifacts directory
+const ReportFilename = "report.html"
+
+// section is a navigable group of figures
+type section struct {
+	ID      string
+	Title   string
+	Figures []figure
+}
+
+type figure struct {
+	Metric string
+	SVG    template.HTML
+}
+
+// experimentRow is a single row of the per-experiment tables
+type experimentRow struct {
+	Conf              input.GenAIPerfExpConf
+	Load              string // Concurrency, or offered and achieved request rates
+	Perf              input.GenAIPerfMetrics
+	Power             input.KeplerPowerMetrics
+	EnergyPerToken    float64
+	Idle              input.KeplerPowerMetrics
+	Net               input.KeplerPowerMetrics
+	NetEnergyPerToken float64
+	PowerW            input.PowerSummary
+	Footprint         input.Footprint
+	CarbonPerRequest  float64
+	CarbonPerMTokens  float64
+	CostPerRequest    float64
+	CostPerMTokens    float64
+	Reps              int
+	ExpectedReps      int  // Repetitions of the sweep, more than Reps when some are missing
+	Partial           bool // Still running, read from a checkpoint
+	// 95% CI half width over the repetitions of the metrics in ciMetrics, 0 without repetitions
+	CI map[string]float64
+}
+
+// ciMetrics are the metrics shown with their confidence interval in the tables
+var ciMetrics = map[string]func(input.ExpMetrics) float64{
+	"RequestThroughput":     func(m input.ExpMetrics) float64 { return m.PerfM.RequestThroughput },
+	"OutputTokenThroughput": func(m input.ExpMetrics) float64 { return m.PerfM.OutputTokenThroughput },
+	"AvgTTFTMs":             func(m input.ExpMetrics) float64 { return m.PerfM.AvgTTFTMs },
+	"AvgITLMs":              func(m input.ExpMetrics) float64 { return m.PerfM.AvgITLMs },
+	"AvgRequestLatencyMs":   func(m input.ExpMetrics) float64 { return m.PerfM.AvgRequestLatencyMs },
+	"NodePlatformJ":         func(m input.ExpMetrics) float64 { return m.PowerM.NodePlatformJ },
+	"EnergyPerToken":        input.ExpMetrics.EnergyPerToken,
+	"NetNodePlatformJ":      func(m input.ExpMetrics) float64 { return m.NetM.NodePlatformJ },
+	"NetEnergyPerToken":     input.ExpMetrics.NetEnergyPerToken,
+	"CarbonPerMTokens":      input.ExpMetrics.CarbonPerMTokens,
+	"CostPerMTokens":        input.ExpMetrics.CostPerMTokens,
+}
+
+type reportData struct {
+	GeneratedAt    string
+	ArtfDir        string
+	EnergySource   string
+	NumExperiments int
+	NumPartial     int    // Experiments still running
+	LoadHeader     string // Concurrency, or Load when some experiments were run at a request rate
+	TotalRequests  int
+	InputTokens    int
+	OutputTokens   int
+	TotalEnergyJ   total
+	TotalCarbonG   total // Missing without a grid carbon intensity
+	TotalCost      total // Missing without an electricity price
+	Currency       string
+	Matrix         config.GenAIPerf
+	Experiments    []experimentRow
+	ByModel        []section // Figures comparing lengths, one section per model
+	ByLength       []section // Figures comparing models, one section per input/output length
+	Power          []section // Power over time of each experiment, one section per model
+}
+
+// total sums the values of the experiments, the missing (NaN) ones are counted apart
+type total struct {
+	Sum     float64
+	Missing int
+	Count   int
+}
+
+// add adds v to the total
+func (t *total) add(v float64) {
+	t.Count++
+	if math.IsNaN(v) {
+		t.Missing++
+		return
+	}
+	t.Sum += v
+}
+
+// formatTotal formats the total, followed by the number of missing values, N/A when every value is missing
+func formatTotal(format string, t total) string {
+	if t.Missing == t.Count {
+		return "N/A"
+	}
+	s := fmt.Sprintf(format, t.Sum)
+	if t.Missing > 0 {
+		s += fmt.Sprintf(" (%d missing)", t.Missing)
+	}
+	return s
+}
+
+var nonIDChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// sectionID turns a section title into an HTML anchor
+func sectionID(prefix, title string) string {
+	return prefix + "-" + strings.Trim(strings.ToLower(nonIDChars.ReplaceAllString(title, "-")), "-")
+}
+
+// groupFigures renders the figures as SVG and groups them into sections, keeping their order
+func groupFigures(prefix string, figs []plot.Figure) ([]section, error) {
+	var sections []section
+	index := make(map[string]int)
+	for _, f := range figs {
+		svg, err := f.SVG()
+		if err != nil {
+			return nil, fmt.Errorf("rendering plot %s as SVG: %v", f.Filename, err)
+		}
+		// Drop the XML prolog, the SVG is inlined in the HTML document
+		if idx := bytes.Index(svg, []byte("<svg")); idx > 0 {
+			svg = svg[idx:]
+		}
+
+		i, ok := index[f.Group]
+		if !ok {
+			i = len(sections)
+			index[f.Group] = i
+			sections = append(sections, section{ID: sectionID(prefix, f.Group), Title: f.Group})
+		}
+		sections[i].Figures = append(sections[i].Figures, figure{
+			Metric: f.Metric,
+			SVG:    template.HTML(svg),
+		})
+	}
+	return sections, nil
+}
+
+// formatFloat formats v, missing (NaN) values are shown as N/A
+func formatFloat(format string, v float64) string {
+	if math.IsNaN(v) {
+		return "N/A"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+// GenerateReport writes a self-contained HTML report with inlined SVG plots into the artifacts directory
+func GenerateReport(c config.Config, emp input.ExpMetricPair, logger *slog.Logger) (string, error) {
+	byModelFigs, byLengthFigs, err := plot.BuildFigures(emp, logger)
+	if err != nil {
+		return "", err
+	}
+
+	data := reportData{
+		GeneratedAt:  time.Now().Format(time.RFC1123),
+		ArtfDir:      c.ReportConf.ArtfDir,
+		EnergySource: c.ReportConf.EnergySource.Type,
+		Matrix:       c.GenAIPerf,
+		Currency:     c.ReportConf.Footprint.Currency,
+		LoadHeader:   "Concurrency",
+	}
+	if data.EnergySource == "" {
+		data.EnergySource = config.EnergySourceKepler
+	}
+	for _, ec := range emp.SortedConfs() {
+		em := emp[ec]
+		row := experimentRow{
+			Conf:              ec,
+			Load:              fmt.Sprint(ec.Concurrency),
+			Perf:              em.PerfM,
+			Power:             em.PowerM,
+			EnergyPerToken:    em.EnergyPerToken(),
+			Idle:              em.IdleM,
+			Net:               em.NetM,
+			NetEnergyPerToken: em.NetEnergyPerToken(),
+			PowerW:            em.PowerW,
+			Footprint:         em.Footprint,
+			CarbonPerRequest:  em.CarbonPerRequest(),
+			CarbonPerMTokens:  em.CarbonPerMTokens(),
+			CostPerRequest:    em.CostPerRequest(),
+			CostPerMTokens:    em.CostPerMTokens(),
+			Reps:              em.Repetitions(),
+			ExpectedReps:      em.ExpectedRepetitions(),
+			Partial:           em.Partial,
+			CI:                make(map[string]float64),
+		}
+		if ec.LoadMode() == input.LoadRequestRate {
+			row.Load = fmt.Sprintf("%s (%.2f achieved)", ec.LoadDesc(), em.PerfM.AchievedRequestRate)
+			data.LoadHeader = "Load"
+		}
+		// Every metric needs an entry, the template passes them to pm and a missing key is no float64
+		for name, value := range ciMetrics {
+			if row.Reps > 1 {
+				row.CI[name] = em.Stats(value).CI95
+			} else {
+				row.CI[name] = 0
+			}
+		}
+		data.Experiments = append(data.Experiments, row)
+		data.NumExperiments++
+		if em.Partial {
+			data.NumPartial++
+		}
+		// Totals cover every repetition
+		reps := em.Reps
+		if len(reps) == 0 {
+			reps = []input.ExpMetrics{em}
+		}
+		for _, rep := range reps {
+			data.TotalRequests += rep.PerfM.NumRequests
+			data.InputTokens += rep.PerfM.TotalInputTokens
+			data.OutputTokens += rep.PerfM.TotalOutputTokens
+			data.TotalEnergyJ.add(rep.PowerM.NodePlatformJ)
+			data.TotalCarbonG.add(rep.Footprint.CarbonG)
+			data.TotalCost.add(rep.Footprint.Cost)
+		}
+	}
+	// by_length figures compare lengths of a model, so they are navigated by model, and vice versa
+	if data.ByModel, err = groupFigures("model", byLengthFigs); err != nil {
+		return "", err
+	}
+	if data.ByLength, err = groupFigures("length", byModelFigs); err != nil {
+		return "", err
+	}
+	if data.Power, err = groupFigures("power", plot.BuildPowerFigures(emp, logger)); err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"f2":    func(v float64) string { return formatFloat("%.2f", v) },
+		"f4":    func(v float64) string { return formatFloat("%.4f", v) },
+		"f6":    func(v float64) string { return formatFloat("%.6f", v) },
+		"total": formatTotal,
+		// Confidence interval suffix, empty without repetitions
+		"pm": func(format string, ci float64) string {
+			if ci == 0 || math.IsNaN(ci) {
+				return ""
+			}
+			return " ± " + fmt.Sprintf(format, ci)
+		},
+	}).Parse(reportTmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing report template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering report: %v", err)
+	}
+	path := filepath.Join(c.ReportConf.ArtfDir, ReportFilename)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("writing report %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// Exporter writes the HTML report
+type Exporter struct {
+	conf   config.Config
+	logger *slog.Logger
+}
+
+func NewExporter(c config.Config, logger *slog.Logger) *Exporter {
+	return &Exporter{conf: c, logger: logger}
+}
+
+func (e *Exporter) Name() string {
+	return "html"
+}
+
+func (e *Exporter) Export(emp input.ExpMetricPair) error {
+	path, err := GenerateReport(e.conf, emp, e.logger)
+	if err != nil {
+		return err
+	}
+	e.logger.Info("HTML report generated", "path", path)
+	return nil
+}
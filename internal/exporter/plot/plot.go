@@ -1,12 +1,15 @@
 package plot
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/explorerray/itpe-report/config"
 	"github.com/explorerray/itpe-report/internal/input"
@@ -24,93 +27,233 @@ func CreatePlotsSubdir(conf config.Config) string {
 	if err := os.MkdirAll(filepath.Join(plotDir, "by_length"), os.ModePerm); err != nil {
 		panic(err)
 	}
+	if err := os.MkdirAll(filepath.Join(plotDir, "power"), os.ModePerm); err != nil {
+		panic(err)
+	}
 	return plotDir
 }
 
-// genPlotterXY converts slices of float64 into a plotter.XYs structure, filtering out zero/NaN values.
-func genPlotterXY(xValues []float64, yValues []float64) plotter.XYs {
+// series holds the values of a metric at each load along with their error (95% CI half width).
+type series struct {
+	y   []float64
+	err []float64
+}
+
+// errorPoints are points with symmetric vertical error bars.
+type errorPoints struct {
+	plotter.XYs
+	plotter.YErrors
+}
+
+// genPlotterXY converts a series into points, filtering out zero/NaN values.
+// The error bars are only returned when at least one point has an error.
+func genPlotterXY(xValues []float64, s series) (plotter.XYs, *errorPoints) {
 	pts := make(plotter.XYs, 0, len(xValues))
+	errs := make(plotter.YErrors, 0, len(xValues))
+	hasErr := false
 	for i := range xValues {
-		if yValues[i] != 0 && !math.IsNaN(yValues[i]) {
-			pts = append(pts, plotter.XY{X: xValues[i], Y: yValues[i]})
+		if s.y[i] != 0 && !math.IsNaN(s.y[i]) {
+			pts = append(pts, plotter.XY{X: xValues[i], Y: s.y[i]})
+			errs = append(errs, struct{ Low, High float64 }{s.err[i], s.err[i]})
+			hasErr = hasErr || s.err[i] > 0
+		}
+	}
+	if !hasErr {
+		return pts, nil
+	}
+	return pts, &errorPoints{XYs: pts, YErrors: errs}
+}
+
+// addSeries adds the line, the points and the error bars of a series to the plot.
+func addSeries(p *plot.Plot, label string, pts plotter.XYs, errPts *errorPoints, styleMgr *styleManager) error {
+	lineStyle, glyphStyle := styleMgr.getStyle(label) // Use label as the unique key
+
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return err
+	}
+	line.LineStyle = lineStyle
+
+	scatter, err := plotter.NewScatter(pts)
+	if err != nil {
+		return err
+	}
+	scatter.GlyphStyle = glyphStyle
+
+	p.Add(line, scatter)
+	if errPts != nil {
+		bars, err := plotter.NewYErrorBars(errPts)
+		if err != nil {
+			return err
 		}
+		bars.LineStyle.Color = lineStyle.Color
+		p.Add(bars)
 	}
-	return pts
+	p.Legend.Add(label, line, scatter)
+	return nil
 }
 
 // lengthKey represents a unique input/output length combination.
 type lengthKey struct {
 	inputMean  int
 	outputMean int
+	mode       string // Streaming mode, only set when the experiments were run in both modes
+}
+
+// labelSuffix returns the suffix telling apart the series of both streaming modes.
+func (lk lengthKey) labelSuffix() string {
+	if lk.mode == "" {
+		return ""
+	}
+	return " (" + lk.mode + ")"
 }
 
-// modelGroup represents a unique model and parameter size combination.
+// modelGroup represents a unique model along with its metadata.
 type modelGroup struct {
-	model  string
-	pmSize int
+	model        string // Served model, i.e. name[:tag]
+	family       string
+	quantization string
+	pmSize       float64
+}
+
+// describe returns the model metadata for plot titles, e.g. "llama3.2:1b, llama, 1b Parameters".
+func (mg modelGroup) describe() string {
+	parts := []string{mg.model}
+	if mg.family != "" {
+		parts = append(parts, mg.family)
+	}
+	parts = append(parts, sizeTitle(mg.pmSize))
+	if mg.quantization != "" {
+		parts = append(parts, mg.quantization)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sizeTitle returns the parameter size for plot titles.
+func sizeTitle(pmSize float64) string {
+	if pmSize <= 0 {
+		return "Unknown Size"
+	}
+	return input.FormatParamSize(pmSize) + " Parameters"
+}
+
+// fileSafe replaces the characters of s that are not safe in a file name.
+func fileSafe(s string) string {
+	return strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(s)
+}
+
+// sortedLengthKeys returns the keys of m sorted by input length, output length then streaming mode.
+func sortedLengthKeys[V any](m map[lengthKey]V) []lengthKey {
+	keys := make([]lengthKey, 0, len(m))
+	for lk := range m {
+		keys = append(keys, lk)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].inputMean != keys[j].inputMean {
+			return keys[i].inputMean < keys[j].inputMean
+		}
+		if keys[i].outputMean != keys[j].outputMean {
+			return keys[i].outputMean < keys[j].outputMean
+		}
+		return keys[i].mode > keys[j].mode // streaming first
+	})
+	return keys
+}
+
+// sortedModelGroups returns the keys of m sorted by parameter size then model name.
+func sortedModelGroups[V any](m map[modelGroup]V) []modelGroup {
+	keys := make([]modelGroup, 0, len(m))
+	for mg := range m {
+		keys = append(keys, mg)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pmSize != keys[j].pmSize {
+			return keys[i].pmSize < keys[j].pmSize
+		}
+		return keys[i].model < keys[j].model
+	})
+	return keys
+}
+
+// Figure is a generated plot along with where it belongs in a report.
+type Figure struct {
+	Plot     *plot.Plot
+	Metric   string
+	Group    string // e.g. "Input 150" for by_model plots, "llama3.2:1b" for by_length plots
+	Filename string // Path relative to the plot directory, without extension
+}
+
+// SVG renders the figure as an SVG document.
+func (f Figure) SVG() ([]byte, error) {
+	w, err := f.Plot.WriterTo(5*vg.Inch, 5*vg.Inch, "svg")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // MetricByLengthData groups metric data first by metric name, then by lengthKey, then by modelGroup.
-type MetricByLengthData map[string]map[lengthKey]map[modelGroup][]float64
+type MetricByLengthData map[string]map[lengthKey]map[modelGroup]series
 
 // MetricByModelData groups metric data first by metric name, then by modelGroup, then by lengthKey.
-type MetricByModelData map[string]map[modelGroup]map[lengthKey][]float64
+type MetricByModelData map[string]map[modelGroup]map[lengthKey]series
 
 // collectMetricData processes the raw experiment data and organizes it into structures suitable for plotting.
 func collectMetricData(emp input.ExpMetricPair) (MetricByLengthData, MetricByModelData, []float64, []int, []int, error) {
 	// This function remains largely the same, but is a critical part of the data pipeline.
 	// It extracts, sorts, and organizes all data points before they are plotted.
 	type metricValues struct {
-		concurrency int
-		values      config.YPlot
+		load   float64 // Concurrency or request rate
+		values config.YPlot
+		errs   config.YPlot // 95% CI half width over the repetitions
 	}
+	modes := make(map[bool]bool)
+	for ec := range emp {
+		modes[ec.Stream] = true
+	}
+
 	dataByLengthAndModel := make(map[lengthKey]map[modelGroup][]metricValues)
 	inputMeans := make(map[int]bool)
 	outputMeans := make(map[int]bool)
 	for ec, mp := range emp {
 		lk := lengthKey{inputMean: ec.InputMean, outputMean: ec.OutputMean}
-		mg := modelGroup{model: ec.Model, pmSize: ec.PMSize}
+		if len(modes) > 1 {
+			// Both modes side by side
+			lk.mode = ec.Mode()
+		}
+		mg := modelGroup{model: ec.ServedModel(), family: ec.Family, quantization: ec.Quantization, pmSize: ec.PMSize}
 		if _, exists := dataByLengthAndModel[lk]; !exists {
 			dataByLengthAndModel[lk] = make(map[modelGroup][]metricValues)
 		}
 		dataByLengthAndModel[lk][mg] = append(dataByLengthAndModel[lk][mg], metricValues{
-			concurrency: ec.Concurrency,
-			values: config.YPlot{
-				RequestThroughput:     mp.PerfM.RequestThroughput,
-				OutputTokenThroughput: mp.PerfM.OutputTokenThroughput,
-				AvgRequestLatencyMs:   mp.PerfM.AvgRequestLatencyMs,
-				AvgTTFTMs:             mp.PerfM.AvgTTFTMs,
-				AvgITLMs:              mp.PerfM.AvgITLMs,
-				NodePlatformJ:         mp.PowerM.NodePlatformJ,
-				NodeGPUJ:              mp.PowerM.NodeGPUJ,
-				NodeCPUJ:              mp.PowerM.NodePackageJ,
-				EnergyPerToken:        mp.PowerM.NodePlatformJ / float64(mp.PerfM.TotalOutputTokens),
-			},
+			load:   ec.Load(),
+			values: newYPlot(mp),
+			errs:   ci95YPlot(mp),
 		})
 		inputMeans[ec.InputMean] = true
 		outputMeans[ec.OutputMean] = true
 	}
 
-	var allConcurrencies []int
-	concurrencySet := make(map[int]bool)
+	var xValues []float64
+	loadSet := make(map[float64]bool)
 	for _, modelData := range dataByLengthAndModel {
 		for _, metrics := range modelData {
 			for _, mv := range metrics {
-				if !concurrencySet[mv.concurrency] {
-					concurrencySet[mv.concurrency] = true
-					allConcurrencies = append(allConcurrencies, mv.concurrency)
+				if !loadSet[mv.load] {
+					loadSet[mv.load] = true
+					xValues = append(xValues, mv.load)
 				}
 			}
 		}
 	}
-	if len(allConcurrencies) == 0 {
+	if len(xValues) == 0 {
 		return nil, nil, nil, nil, nil, fmt.Errorf("no data found in emp")
 	}
-	sort.Ints(allConcurrencies)
-	xValues := make([]float64, len(allConcurrencies))
-	for i, c := range allConcurrencies {
-		xValues[i] = float64(c)
-	}
+	sort.Float64s(xValues)
 
 	var uniqueInputMeans, uniqueOutputMeans []int
 	for im := range inputMeans {
@@ -126,90 +269,213 @@ func collectMetricData(emp input.ExpMetricPair) (MetricByLengthData, MetricByMod
 	metricsByModel := make(MetricByModelData)
 	metricsConfig := config.GetMetricsConfig()
 	for metricName := range metricsConfig {
-		metricsByLength[metricName] = make(map[lengthKey]map[modelGroup][]float64)
-		metricsByModel[metricName] = make(map[modelGroup]map[lengthKey][]float64)
+		metricsByLength[metricName] = make(map[lengthKey]map[modelGroup]series)
+		metricsByModel[metricName] = make(map[modelGroup]map[lengthKey]series)
 	}
 	for lk, modelData := range dataByLengthAndModel {
 		for mg, metrics := range modelData {
 			for metricName := range metricsConfig {
 				if _, exists := metricsByLength[metricName][lk]; !exists {
-					metricsByLength[metricName][lk] = make(map[modelGroup][]float64)
+					metricsByLength[metricName][lk] = make(map[modelGroup]series)
 				}
 				if _, exists := metricsByModel[metricName][mg]; !exists {
-					metricsByModel[metricName][mg] = make(map[lengthKey][]float64)
+					metricsByModel[metricName][mg] = make(map[lengthKey]series)
 				}
-				yValues := make([]float64, len(allConcurrencies))
-				concurrencyIndexMap := make(map[int]int)
-				for i, c := range allConcurrencies {
-					concurrencyIndexMap[c] = i
+				values := series{y: make([]float64, len(xValues)), err: make([]float64, len(xValues))}
+				loadIndexMap := make(map[float64]int)
+				for i, l := range xValues {
+					loadIndexMap[l] = i
 				}
 				for _, mv := range metrics {
-					idx, ok := concurrencyIndexMap[mv.concurrency]
+					idx, ok := loadIndexMap[mv.load]
 					if !ok {
 						continue
 					}
-					switch metricName {
-					case "Request Throughput":
-						yValues[idx] = mv.values.RequestThroughput
-					case "Output Token Throughput":
-						yValues[idx] = mv.values.OutputTokenThroughput
-					case "Avg Request Latency":
-						yValues[idx] = mv.values.AvgRequestLatencyMs
-					case "Avg TTFT":
-						yValues[idx] = mv.values.AvgTTFTMs
-					case "Avg ITL":
-						yValues[idx] = mv.values.AvgITLMs
-					case "Node Platform":
-						yValues[idx] = mv.values.NodePlatformJ
-					case "Node GPU":
-						yValues[idx] = mv.values.NodeGPUJ
-					case "Node CPU":
-						yValues[idx] = mv.values.NodeCPUJ
-					case "Energy Per Token":
-						yValues[idx] = mv.values.EnergyPerToken
-					}
+					values.y[idx] = yPlotValue(mv.values, metricName)
+					values.err[idx] = yPlotValue(mv.errs, metricName)
 				}
-				metricsByLength[metricName][lk][mg] = yValues
-				metricsByModel[metricName][mg][lk] = yValues
+				metricsByLength[metricName][lk][mg] = values
+				metricsByModel[metricName][mg][lk] = values
 			}
 		}
 	}
 	return metricsByLength, metricsByModel, xValues, uniqueInputMeans, uniqueOutputMeans, nil
 }
 
+// newYPlot extracts the plotted metrics of an experiment.
+func newYPlot(m input.ExpMetrics) config.YPlot {
+	return config.YPlot{
+		RequestThroughput:      m.PerfM.RequestThroughput,
+		OutputTokenThroughput:  m.PerfM.OutputTokenThroughput,
+		AvgRequestLatencyMs:    m.PerfM.AvgRequestLatencyMs,
+		AvgTTFTMs:              m.PerfM.AvgTTFTMs,
+		AvgITLMs:               m.PerfM.AvgITLMs,
+		P50TTFTMs:              m.PerfM.TTFTStatsMs.P50,
+		P90TTFTMs:              m.PerfM.TTFTStatsMs.P90,
+		P95TTFTMs:              m.PerfM.TTFTStatsMs.P95,
+		P99TTFTMs:              m.PerfM.TTFTStatsMs.P99,
+		MinTTFTMs:              m.PerfM.TTFTStatsMs.Min,
+		MaxTTFTMs:              m.PerfM.TTFTStatsMs.Max,
+		StddevTTFTMs:           m.PerfM.TTFTStatsMs.Stddev,
+		P50ITLMs:               m.PerfM.ITLStatsMs.P50,
+		P90ITLMs:               m.PerfM.ITLStatsMs.P90,
+		P95ITLMs:               m.PerfM.ITLStatsMs.P95,
+		P99ITLMs:               m.PerfM.ITLStatsMs.P99,
+		MinITLMs:               m.PerfM.ITLStatsMs.Min,
+		MaxITLMs:               m.PerfM.ITLStatsMs.Max,
+		StddevITLMs:            m.PerfM.ITLStatsMs.Stddev,
+		P50RequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.P50,
+		P90RequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.P90,
+		P95RequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.P95,
+		P99RequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.P99,
+		MinRequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.Min,
+		MaxRequestLatencyMs:    m.PerfM.RequestLatencyStatsMs.Max,
+		StddevRequestLatencyMs: m.PerfM.RequestLatencyStatsMs.Stddev,
+		NodePlatformJ:          m.PowerM.NodePlatformJ,
+		NodeGPUJ:               m.PowerM.NodeGPUJ,
+		NodeCPUJ:               m.PowerM.NodePackageJ,
+		EnergyPerToken:         m.EnergyPerToken(),
+		NetNodePlatformJ:       m.NetM.NodePlatformJ,
+		NetEnergyPerToken:      m.NetEnergyPerToken(),
+		CarbonPerMTokens:       m.CarbonPerMTokens(),
+		CostPerMTokens:         m.CostPerMTokens(),
+	}
+}
+
+// ci95YPlot returns the half width of the 95% confidence interval of every plotted metric
+// over the repetitions of an experiment, all 0 without repetitions.
+func ci95YPlot(m input.ExpMetrics) config.YPlot {
+	var ci config.YPlot
+	if len(m.Reps) < 2 {
+		return ci
+	}
+	reps := make([]reflect.Value, len(m.Reps))
+	for i, rep := range m.Reps {
+		reps[i] = reflect.ValueOf(newYPlot(rep))
+	}
+	v := reflect.ValueOf(&ci).Elem()
+	values := make([]float64, len(reps))
+	for f := 0; f < v.NumField(); f++ {
+		for i, rep := range reps {
+			values[i] = rep.Field(f).Float()
+		}
+		v.Field(f).SetFloat(input.NewRepStats(values).CI95)
+	}
+	return ci
+}
+
+// yPlotValue returns the value of metricName in v.
+func yPlotValue(v config.YPlot, metricName string) float64 {
+	switch metricName {
+	case "Request Throughput":
+		return v.RequestThroughput
+	case "Output Token Throughput":
+		return v.OutputTokenThroughput
+	case "Avg Request Latency":
+		return v.AvgRequestLatencyMs
+	case "Avg TTFT":
+		return v.AvgTTFTMs
+	case "Avg ITL":
+		return v.AvgITLMs
+	case "P50 TTFT":
+		return v.P50TTFTMs
+	case "P90 TTFT":
+		return v.P90TTFTMs
+	case "P95 TTFT":
+		return v.P95TTFTMs
+	case "P99 TTFT":
+		return v.P99TTFTMs
+	case "Min TTFT":
+		return v.MinTTFTMs
+	case "Max TTFT":
+		return v.MaxTTFTMs
+	case "Stddev TTFT":
+		return v.StddevTTFTMs
+	case "P50 ITL":
+		return v.P50ITLMs
+	case "P90 ITL":
+		return v.P90ITLMs
+	case "P95 ITL":
+		return v.P95ITLMs
+	case "P99 ITL":
+		return v.P99ITLMs
+	case "Min ITL":
+		return v.MinITLMs
+	case "Max ITL":
+		return v.MaxITLMs
+	case "Stddev ITL":
+		return v.StddevITLMs
+	case "P50 Request Latency":
+		return v.P50RequestLatencyMs
+	case "P90 Request Latency":
+		return v.P90RequestLatencyMs
+	case "P95 Request Latency":
+		return v.P95RequestLatencyMs
+	case "P99 Request Latency":
+		return v.P99RequestLatencyMs
+	case "Min Request Latency":
+		return v.MinRequestLatencyMs
+	case "Max Request Latency":
+		return v.MaxRequestLatencyMs
+	case "Stddev Request Latency":
+		return v.StddevRequestLatencyMs
+	case "Node Platform":
+		return v.NodePlatformJ
+	case "Node GPU":
+		return v.NodeGPUJ
+	case "Node CPU":
+		return v.NodeCPUJ
+	case "Energy Per Token":
+		return v.EnergyPerToken
+	case "Net Node Platform":
+		return v.NetNodePlatformJ
+	case "Net Energy Per Token":
+		return v.NetEnergyPerToken
+	case "Carbon Per 1M Tokens":
+		return v.CarbonPerMTokens
+	case "Cost Per 1M Tokens":
+		return v.CostPerMTokens
+	}
+	return 0
+}
+
 // createMetricPlotByModel generates a plot, using the styleManager for consistent line styles.
-func createMetricPlotByModel(metricName string, pmSize int, groupBy string, groupValue int, dataByLength map[lengthKey]map[modelGroup][]float64, xValues []float64, plotDir string, styleMgr *styleManager, logger *slog.Logger) error {
+// It returns a nil figure if there is no data to plot.
+func createMetricPlotByModel(metricName string, pmSize float64, groupBy string, groupValue int, dataByLength map[lengthKey]map[modelGroup]series, xValues []float64, axis loadAxis, styleMgr *styleManager, logger *slog.Logger) (*Figure, error) {
 	metricConfig, exists := config.GetMetricsConfig()[metricName]
 	if !exists {
-		return fmt.Errorf("unknown metric: %s", metricName)
+		return nil, fmt.Errorf("unknown metric: %s", metricName)
 	}
 
-	var title, filename string
+	var title, filename, group string
 	if groupBy == "input" {
-		title = fmt.Sprintf("%s (%db Parameters, Input %d)", metricName, pmSize, groupValue)
-		filename = fmt.Sprintf("by_model/%s_%db_input%d.png", metricConfig.Filename, pmSize, groupValue)
+		title = fmt.Sprintf("%s (%s, Input %d%s)", metricName, sizeTitle(pmSize), groupValue, axis.titleSuffix())
+		filename = fmt.Sprintf("by_model/%s_%s_input%d%s", metricConfig.Filename, input.FormatParamSize(pmSize), groupValue, axis.fileSuffix())
+		group = fmt.Sprintf("Input %d%s", groupValue, axis.groupSuffix())
 	} else {
-		title = fmt.Sprintf("%s (%db Parameters, Output %d)", metricName, pmSize, groupValue)
-		filename = fmt.Sprintf("by_model/%s_%db_output%d.png", metricConfig.Filename, pmSize, groupValue)
+		title = fmt.Sprintf("%s (%s, Output %d%s)", metricName, sizeTitle(pmSize), groupValue, axis.titleSuffix())
+		filename = fmt.Sprintf("by_model/%s_%s_output%d%s", metricConfig.Filename, input.FormatParamSize(pmSize), groupValue, axis.fileSuffix())
+		group = fmt.Sprintf("Output %d%s", groupValue, axis.groupSuffix())
 	}
 
 	p := plot.New()
 	p.Title.Text = title
-	p.X.Label.Text = "Concurrency"
+	p.X.Label.Text = axis.label()
 	p.Y.Label.Text = metricConfig.YLabel
 	p.Legend.Top = true
 	p.Legend.XOffs = -vg.Points(10)
 
 	hasData := false
-	for lk, modelData := range dataByLength {
+	for _, lk := range sortedLengthKeys(dataByLength) {
 		if (groupBy == "input" && lk.inputMean != groupValue) || (groupBy == "output" && lk.outputMean != groupValue) {
 			continue
 		}
-		for mg, yValues := range modelData {
+		modelData := dataByLength[lk]
+		for _, mg := range sortedModelGroups(modelData) {
 			if mg.pmSize != pmSize {
 				continue
 			}
-			pts := genPlotterXY(xValues, yValues)
+			pts, errPts := genPlotterXY(xValues, modelData[mg])
 			if len(pts) == 0 {
 				continue
 			}
@@ -217,150 +483,237 @@ func createMetricPlotByModel(metricName string, pmSize int, groupBy string, grou
 
 			var label string
 			if groupBy == "input" {
-				label = fmt.Sprintf("%s-output%d", mg.model, lk.outputMean)
+				label = fmt.Sprintf("%s-output%d%s", mg.model, lk.outputMean, lk.labelSuffix())
 			} else {
-				label = fmt.Sprintf("%s-input%d", mg.model, lk.inputMean)
+				label = fmt.Sprintf("%s-input%d%s", mg.model, lk.inputMean, lk.labelSuffix())
 			}
-
-			lineStyle, glyphStyle := styleMgr.getStyle(label) // Use label as the unique key
-
-			line, err := plotter.NewLine(pts)
-			if err != nil {
-				return err
+			if err := addSeries(p, label, pts, errPts, styleMgr); err != nil {
+				return nil, err
 			}
-			line.LineStyle = lineStyle
-
-			scatter, err := plotter.NewScatter(pts)
-			if err != nil {
-				return err
-			}
-			scatter.GlyphStyle = glyphStyle
-
-			p.Add(line, scatter)
-			p.Legend.Add(label, line, scatter)
 		}
 	}
 
 	if !hasData {
 		logger.Info("Skipping plot due to no data", "title", title)
-		return nil // Not a fatal error, just no data to plot.
+		return nil, nil // Not a fatal error, just no data to plot.
 	}
 
-	filepath := filepath.Join(plotDir, filename)
-	if err := p.Save(5*vg.Inch, 5*vg.Inch, filepath); err != nil {
-		return fmt.Errorf("failed to save plot %s: %v", filepath, err)
-	}
-	return nil
+	return &Figure{Plot: p, Metric: metricName, Group: group, Filename: filename}, nil
 }
 
 // createMetricPlotByLength generates a plot, using the styleManager for consistent line styles.
-func createMetricPlotByLength(metricName string, mg modelGroup, dataByLength map[lengthKey][]float64, xValues []float64, plotDir string, styleMgr *styleManager, logger *slog.Logger) error {
+// It returns a nil figure if there is no data to plot.
+func createMetricPlotByLength(metricName string, mg modelGroup, dataByLength map[lengthKey]series, xValues []float64, axis loadAxis, styleMgr *styleManager, logger *slog.Logger) (*Figure, error) {
 	metricConfig, exists := config.GetMetricsConfig()[metricName]
 	if !exists {
-		return fmt.Errorf("unknown metric: %s", metricName)
+		return nil, fmt.Errorf("unknown metric: %s", metricName)
 	}
 
 	p := plot.New()
-	p.Title.Text = fmt.Sprintf("%s (%s, %db Parameters)", metricName, mg.model, mg.pmSize)
-	p.X.Label.Text = "Concurrency"
+	p.Title.Text = fmt.Sprintf("%s (%s%s)", metricName, mg.describe(), axis.titleSuffix())
+	p.X.Label.Text = axis.label()
 	p.Y.Label.Text = metricConfig.YLabel
 	p.Legend.Top = true
 	p.Legend.XOffs = -vg.Points(10)
 
-	var sortedKeys []lengthKey
-	for lk := range dataByLength {
-		sortedKeys = append(sortedKeys, lk)
-	}
-	sort.Slice(sortedKeys, func(i, j int) bool {
-		if sortedKeys[i].inputMean != sortedKeys[j].inputMean {
-			return sortedKeys[i].inputMean < sortedKeys[j].inputMean
-		}
-		return sortedKeys[i].outputMean < sortedKeys[j].outputMean
-	})
-
 	hasData := false
-	for _, lk := range sortedKeys {
-		yValues := dataByLength[lk]
-		pts := genPlotterXY(xValues, yValues)
+	for _, lk := range sortedLengthKeys(dataByLength) {
+		pts, errPts := genPlotterXY(xValues, dataByLength[lk])
 		if len(pts) == 0 {
 			continue
 		}
 		hasData = true
 
-		label := fmt.Sprintf("in%d/out%d", lk.inputMean, lk.outputMean)
-		lineStyle, glyphStyle := styleMgr.getStyle(label) // Use label as the unique key
-
-		line, err := plotter.NewLine(pts)
-		if err != nil {
-			return err
+		label := fmt.Sprintf("in%d/out%d%s", lk.inputMean, lk.outputMean, lk.labelSuffix())
+		if err := addSeries(p, label, pts, errPts, styleMgr); err != nil {
+			return nil, err
 		}
-		line.LineStyle = lineStyle
-
-		scatter, err := plotter.NewScatter(pts)
-		if err != nil {
-			return err
-		}
-		scatter.GlyphStyle = glyphStyle
-
-		p.Add(line, scatter)
-		p.Legend.Add(label, line, scatter)
 	}
 
 	if !hasData {
 		logger.Info("Skipping plot due to no data", "title", p.Title.Text)
-		return nil
+		return nil, nil
 	}
 
-	filename := fmt.Sprintf("by_length/%s_%s_%db.png", metricConfig.Filename, mg.model, mg.pmSize)
-	filepath := filepath.Join(plotDir, filename)
-	if err := p.Save(5*vg.Inch, 5*vg.Inch, filepath); err != nil {
-		return fmt.Errorf("failed to save plot %s: %v", filepath, err)
+	filename := fmt.Sprintf("by_length/%s_%s%s", metricConfig.Filename, fileSafe(mg.model), axis.fileSuffix())
+	group := mg.model + axis.groupSuffix()
+	return &Figure{Plot: p, Metric: metricName, Group: group, Filename: filename}, nil
+}
+
+// loadAxis is the X axis of the plots of the experiments run in one load mode
+type loadAxis struct {
+	mode  string // input.LoadConcurrency or input.LoadRequestRate
+	mixed bool   // Both load modes were run, their plots are told apart
+}
+
+// label returns the label of the axis
+func (a loadAxis) label() string {
+	if a.mode == input.LoadRequestRate {
+		return "Request Rate (req/s)"
 	}
-	return nil
+	return "Concurrency"
 }
 
-// GeneratePlots coordinates the entire plot generation process.
-func GeneratePlots(emp input.ExpMetricPair, plotDir string, logger *slog.Logger) error {
-	metricsByLength, metricsByModel, xValues, inputMeans, outputMeans, err := collectMetricData(emp)
-	if err != nil {
-		return fmt.Errorf("failed to collect metric data: %v", err)
+// titleSuffix returns the suffix of the plot titles, e.g. ", request rate" when both load modes are plotted
+func (a loadAxis) titleSuffix() string {
+	if !a.mixed {
+		return ""
+	}
+	return ", " + strings.ReplaceAll(a.mode, "_", " ")
+}
+
+// groupSuffix returns the suffix of the figure groups, e.g. " (request rate)" when both load modes are plotted
+func (a loadAxis) groupSuffix() string {
+	if !a.mixed {
+		return ""
+	}
+	return " (" + strings.ReplaceAll(a.mode, "_", " ") + ")"
+}
+
+// fileSuffix returns the suffix of the plot file names, e.g. "_request_rate" when both load modes are plotted
+func (a loadAxis) fileSuffix() string {
+	if !a.mixed {
+		return ""
+	}
+	return "_" + a.mode
+}
+
+// splitByLoadMode splits the experiments by load mode, concurrency first, along with the X axis of their plots
+func splitByLoadMode(emp input.ExpMetricPair) ([]input.ExpMetricPair, []loadAxis) {
+	byMode := make(map[string]input.ExpMetricPair)
+	for ec, em := range emp {
+		if byMode[ec.LoadMode()] == nil {
+			byMode[ec.LoadMode()] = make(input.ExpMetricPair)
+		}
+		byMode[ec.LoadMode()][ec] = em
+	}
+	var emps []input.ExpMetricPair
+	var axes []loadAxis
+	for _, mode := range []string{input.LoadConcurrency, input.LoadRequestRate} {
+		if byMode[mode] != nil {
+			emps = append(emps, byMode[mode])
+			axes = append(axes, loadAxis{mode: mode, mixed: len(byMode) > 1})
+		}
+	}
+	return emps, axes
+}
+
+// BuildFigures builds every plot of the experiments, in a deterministic order:
+// first the plots comparing models (by_model), grouped by input then output length,
+// then the plots comparing lengths (by_length), grouped by model.
+// The X axis is the concurrency or the request rate, the experiments of each load mode are plotted apart.
+func BuildFigures(emp input.ExpMetricPair, logger *slog.Logger) (byModel []Figure, byLength []Figure, err error) {
+	emps, axes := splitByLoadMode(emp)
+	if len(emps) == 0 {
+		return nil, nil, fmt.Errorf("failed to collect metric data: no data found in emp")
 	}
 
 	styleMgrForModelPlots := newStyleManager()
 	styleMgrForLengthPlots := newStyleManager()
+	for i, modeEmp := range emps {
+		m, l, err := buildLoadFigures(modeEmp, axes[i], styleMgrForModelPlots, styleMgrForLengthPlots, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		byModel = append(byModel, m...)
+		byLength = append(byLength, l...)
+	}
+	return byModel, byLength, nil
+}
+
+// buildLoadFigures builds the plots of the experiments run in the load mode of axis
+func buildLoadFigures(emp input.ExpMetricPair, axis loadAxis, styleMgrForModelPlots, styleMgrForLengthPlots *styleManager, logger *slog.Logger) (byModel []Figure, byLength []Figure, err error) {
+	metricsByLength, metricsByModel, xValues, inputMeans, outputMeans, err := collectMetricData(emp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect metric data: %v", err)
+	}
 
-	// Generate plots grouped by model parameters
-	for metricName, dataByLength := range metricsByLength {
-		byPMSize := make(map[int]bool)
+	// Plots grouped by model parameters
+	var pmSizes []float64
+	byPMSize := make(map[float64]bool)
+	for _, dataByLength := range metricsByLength {
 		for _, modelData := range dataByLength {
 			for mg := range modelData {
-				byPMSize[mg.pmSize] = true
-			}
-		}
-
-		for pmSize := range byPMSize {
-			for _, inputMean := range inputMeans {
-				if err := createMetricPlotByModel(metricName, pmSize, "input", inputMean, dataByLength, xValues, plotDir, styleMgrForModelPlots, logger); err != nil {
-					logger.Error("Failed to create plot by model (input)", "error", err, "metricName", metricName, "pmSize", pmSize, "inputMean", inputMean)
+				if !byPMSize[mg.pmSize] {
+					byPMSize[mg.pmSize] = true
+					pmSizes = append(pmSizes, mg.pmSize)
 				}
 			}
-			for _, outputMean := range outputMeans {
-				if err := createMetricPlotByModel(metricName, pmSize, "output", outputMean, dataByLength, xValues, plotDir, styleMgrForModelPlots, logger); err != nil {
-					logger.Error("Failed to create plot by model (output)", "error", err, "metricName", metricName, "pmSize", pmSize, "outputMean", outputMean)
+		}
+	}
+	sort.Float64s(pmSizes)
+	for _, groupBy := range []string{"input", "output"} {
+		groupValues := inputMeans
+		if groupBy == "output" {
+			groupValues = outputMeans
+		}
+		for _, groupValue := range groupValues {
+			for _, pmSize := range pmSizes {
+				for _, metricName := range config.GetMetricNames() {
+					fig, err := createMetricPlotByModel(metricName, pmSize, groupBy, groupValue, metricsByLength[metricName], xValues, axis, styleMgrForModelPlots, logger)
+					if err != nil {
+						logger.Error("Failed to create plot by model", "error", err, "metricName", metricName, "pmSize", pmSize, groupBy, groupValue)
+						continue
+					}
+					if fig != nil {
+						byModel = append(byModel, *fig)
+					}
 				}
 			}
 		}
 	}
 
-	// Generate plots grouped by input/output length
-	for metricName, dataByModel := range metricsByModel {
-		for mg, dataByLength := range dataByModel {
-			if err := createMetricPlotByLength(metricName, mg, dataByLength, xValues, plotDir, styleMgrForLengthPlots, logger); err != nil {
+	// Plots grouped by input/output length, every metric has the same model groups
+	for _, mg := range sortedModelGroups(metricsByModel[config.GetMetricNames()[0]]) {
+		for _, metricName := range config.GetMetricNames() {
+			fig, err := createMetricPlotByLength(metricName, mg, metricsByModel[metricName][mg], xValues, axis, styleMgrForLengthPlots, logger)
+			if err != nil {
 				logger.Error("Failed to create plot by length", "error", err, "metricName", metricName, "modelGroup", mg)
+				continue
+			}
+			if fig != nil {
+				byLength = append(byLength, *fig)
 			}
 		}
 	}
 
+	return byModel, byLength, nil
+}
+
+// GeneratePlots coordinates the entire plot generation process.
+func GeneratePlots(emp input.ExpMetricPair, plotDir string, logger *slog.Logger) error {
+	byModel, byLength, err := BuildFigures(emp, logger)
+	if err != nil {
+		return err
+	}
+
+	figs := append(byModel, byLength...)
+	figs = append(figs, BuildPowerFigures(emp, logger)...)
+	for _, fig := range figs {
+		path := filepath.Join(plotDir, fig.Filename+".png")
+		if err := fig.Plot.Save(5*vg.Inch, 5*vg.Inch, path); err != nil {
+			logger.Error("Failed to save plot", "error", err, "path", path)
+		}
+	}
+
 	logger.Info("Successfully generated all plots.")
 	return nil
 }
+
+// Exporter saves every plot as PNG under the plots directory
+type Exporter struct {
+	conf   config.Config
+	logger *slog.Logger
+}
+
+func NewExporter(c config.Config, logger *slog.Logger) *Exporter {
+	return &Exporter{conf: c, logger: logger}
+}
+
+func (e *Exporter) Name() string {
+	return "plots"
+}
+
+func (e *Exporter) Export(emp input.ExpMetricPair) error {
+	return GeneratePlots(emp, CreatePlotsSubdir(e.conf), e.logger)
+}
@@ -0,0 +1,130 @@
+package plot
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/explorerray/itpe-report/internal/client/promclient"
+	"github.com/explorerray/itpe-report/internal/input"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// PowerMetric is the metric name of the power over time figures.
+const PowerMetric = "Power Over Time"
+
+// requestMarkers returns the points marking the start and the end of every request at y,
+// in seconds since begin.
+func requestMarkers(em input.ExpMetrics, y float64) (starts, ends plotter.XYs) {
+	for _, r := range em.Requests {
+		starts = append(starts, plotter.XY{X: r.Start.Sub(em.Begin).Seconds(), Y: y})
+		ends = append(ends, plotter.XY{X: r.End.Sub(em.Begin).Seconds(), Y: y})
+	}
+	return starts, ends
+}
+
+// createPowerPlot plots the power drawn by each component during an experiment along with
+// the start and the end of its requests. It returns a nil figure if there is no series to plot.
+func createPowerPlot(ec input.GenAIPerfExpConf, em input.ExpMetrics, styleMgr *styleManager) (*Figure, error) {
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Power (%s, in%d/out%d, %s)", ec.ServedModel(), ec.InputMean, ec.OutputMean, ec.LoadDesc())
+	if !ec.Stream {
+		p.Title.Text = fmt.Sprintf("Power (%s, in%d/out%d, %s, %s)", ec.ServedModel(), ec.InputMean, ec.OutputMean, ec.LoadDesc(), ec.Mode())
+	}
+	p.X.Label.Text = "Time Since Experiment Start (s)"
+	p.Y.Label.Text = "Power (W)"
+	p.Y.Min = 0
+	p.Legend.Top = true
+	p.Legend.XOffs = -vg.Points(10)
+
+	hasData := false
+	for _, component := range promclient.KeplerComponents {
+		samples := em.PowerSeries[component]
+		if len(samples) == 0 {
+			continue
+		}
+		hasData = true
+
+		pts := make(plotter.XYs, len(samples))
+		for i, s := range samples {
+			pts[i] = plotter.XY{X: s.Time.Sub(em.Begin).Seconds(), Y: s.Watts}
+		}
+		// Points show the readings of short experiments spanning a few samples
+		line, points, err := plotter.NewLinePoints(pts)
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle, points.GlyphStyle = styleMgr.getStyle(component)
+		points.GlyphStyle.Radius = vg.Points(1.5)
+		p.Add(line, points)
+		p.Legend.Add(component, line, points)
+	}
+	if !hasData {
+		return nil, nil
+	}
+
+	starts, ends := requestMarkers(em, 0)
+	for _, m := range []struct {
+		label string
+		pts   plotter.XYs
+		shape draw.GlyphDrawer
+	}{
+		{"request start", starts, draw.TriangleGlyph{}},
+		{"request end", ends, draw.CrossGlyph{}},
+	} {
+		if len(m.pts) == 0 {
+			continue
+		}
+		scatter, err := plotter.NewScatter(m.pts)
+		if err != nil {
+			return nil, err
+		}
+		_, glyphStyle := styleMgr.getStyle(m.label)
+		glyphStyle.Shape = m.shape
+		scatter.GlyphStyle = glyphStyle
+		p.Add(scatter)
+		p.Legend.Add(m.label, scatter)
+	}
+
+	filename := fmt.Sprintf("power/%s_in%d_out%d_%s_n%d", fileSafe(ec.ServedModel()), ec.InputMean, ec.OutputMean, loadTag(ec), ec.RunCount)
+	if !ec.Stream {
+		filename += "_nostream"
+	}
+	return &Figure{Plot: p, Metric: PowerMetric, Group: ec.ServedModel(), Filename: filename}, nil
+}
+
+// BuildPowerFigures builds the power over time plot of every experiment, in the order of the experiment configs.
+// The series of a repeated experiment is the one of its first repetition.
+func BuildPowerFigures(emp input.ExpMetricPair, logger *slog.Logger) []Figure {
+	styleMgr := newStyleManager()
+	var figs []Figure
+	for _, ec := range emp.SortedConfs() {
+		em := emp[ec]
+		if len(em.Reps) > 0 {
+			em = em.Reps[0]
+		}
+		fig, err := createPowerPlot(ec, em, styleMgr)
+		if err != nil {
+			logger.Error("Failed to create power plot", "error", err, "model", ec.ServedModel(), "load", ec.LoadDesc())
+			continue
+		}
+		if fig != nil {
+			figs = append(figs, *fig)
+		}
+	}
+	if len(figs) == 0 {
+		logger.Info("Skipping power plots due to no power series")
+	}
+	return figs
+}
+
+// loadTag abbreviates the load of the experiment in the plot file names, e.g. c4 or r2.5
+func loadTag(ec input.GenAIPerfExpConf) string {
+	if ec.LoadMode() == input.LoadRequestRate {
+		return "r" + strconv.FormatFloat(ec.RequestRate, 'f', -1, 64)
+	}
+	return fmt.Sprintf("c%d", ec.Concurrency)
+}
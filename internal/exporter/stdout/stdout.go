@@ -2,106 +2,128 @@ package stdout
 
 import (
 	"fmt"
+	"math"
 	"os"
-	"strings"
-	"time"
 
-	"github.com/explorerray/itpe-report/internal/client/promclient"
 	"github.com/explorerray/itpe-report/internal/input"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
 )
 
-// cleanQueryName removes kepler_ prefix and _joules_total or _total suffix
-func cleanQueryName(name string) string {
-	// Remove labels (e.g., {container_name="ollama"}) if present
-	if idx := strings.Index(name, "{"); idx != -1 {
-		name = name[:idx]
-	}
-	// Remove kepler_ prefix
-	name = strings.TrimPrefix(name, "kepler_")
-	// Remove _joules_total* suffix
-	name = strings.Split(name, "_joules_total")[0]
-	return name
-}
-
-func QueryToTableOut(qs []promclient.QueryInfo, qrs []promclient.QueryResponse) {
-	// Create a table
+// ExpMetricsToTableOut renders a single table comparing every experiment of the matrix.
+// Metrics of repeated experiments are shown as mean ± 95% confidence interval.
+func ExpMetricsToTableOut(emp input.ExpMetricPair) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Query", "Value", "Timestamp"})
+	t.AppendHeader(table.Row{"Model", "Input", "Output", loadHeader(emp), "Requests", "Reps",
+		"Avg In Tokens", "Avg Out Tokens", "Req/s", "Tokens/s", "Avg TTFT (ms)", "P99 TTFT (ms)", "Avg ITL (ms)", "P99 ITL (ms)",
+		"Avg Latency (ms)", "P99 Latency (ms)", "Node Platform (J)", "Node GPU (J)", "Pod Platform (J)", "Energy/Token (J)",
+		"Net Platform (J)", "Net Energy/Token (J)", "Avg Power (W)", "Peak Power (W)",
+		"gCO2e/1M Tokens", "Cost/1M Tokens"})
 
-	// Process responses
-	for i, resp := range qrs {
-		queryName := cleanQueryName(qs[i].Name)
-		if resp.Error != nil {
-			fmt.Printf("Error: %v\n", resp.Error)
-			t.AppendRow(table.Row{queryName, "N/A", "N/A"})
-			continue
-		}
-		if len(resp.Warnings) > 0 {
-			fmt.Printf("Warnings for %s: %v\n", queryName, resp.Warnings)
-		}
-		if len(resp.Results) == 0 {
-			t.AppendRow(table.Row{queryName, "N/A", "N/A"})
-			continue
-		}
-		for _, result := range resp.Results {
-			valueStr := fmt.Sprintf("%v", result.Value)
-			timeStr := result.Timestamp.Time().Format(time.RFC3339)
-			t.AppendRow(table.Row{queryName, valueStr, timeStr})
-		}
+	for _, ec := range emp.SortedConfs() {
+		m := emp[ec]
+		t.AppendRow(table.Row{
+			modelCell(ec), ec.InputMean, ec.OutputMean, loadCell(ec, m), requestsCell(ec, m), repsCell(m),
+			fmt.Sprintf("%.1f", m.PerfM.AvgInputTokens), fmt.Sprintf("%.1f", m.PerfM.AvgOutputTokens),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.RequestThroughput }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.OutputTokenThroughput }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.AvgTTFTMs }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.TTFTStatsMs.P99 }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.AvgITLMs }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.ITLStatsMs.P99 }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.AvgRequestLatencyMs }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PerfM.RequestLatencyStatsMs.P99 }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PowerM.NodePlatformJ }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PowerM.NodeGPUJ }),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.PowerM.PodPlatformJ }),
+			withCI(m, "%.4f", input.ExpMetrics.EnergyPerToken),
+			withCI(m, "%.2f", func(m input.ExpMetrics) float64 { return m.NetM.NodePlatformJ }),
+			withCI(m, "%.4f", input.ExpMetrics.NetEnergyPerToken),
+			withCI(m, "%.1f", func(m input.ExpMetrics) float64 { return m.PowerW.NodePlatform.AvgW }),
+			withCI(m, "%.1f", func(m input.ExpMetrics) float64 { return m.PowerW.NodePlatform.PeakW }),
+			withCI(m, "%.2f", input.ExpMetrics.CarbonPerMTokens),
+			withCI(m, "%.4f", input.ExpMetrics.CostPerMTokens),
+		})
+	}
+	// Merge the repeated model cells and right-align the values
+	columnConfigs := []table.ColumnConfig{{Number: 1, AutoMerge: true}}
+	for i := 2; i <= 26; i++ {
+		columnConfigs = append(columnConfigs, table.ColumnConfig{Number: i, Align: text.AlignRight})
 	}
+	t.SetColumnConfigs(columnConfigs)
 
 	// Render the table
-	fmt.Println("Prometheus Query Results:")
+	fmt.Println("Experiment Metrics:")
 	t.Render()
 	fmt.Println()
 }
 
-func PerfMetricsToTableOut(metrics input.GenAIPerfMetrics) {
-	// Create a table
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Metric", "Value"})
+// modelCell returns the served model of the experiment, marked when its responses were not streamed
+func modelCell(ec input.GenAIPerfExpConf) string {
+	if ec.Stream {
+		return ec.ServedModel()
+	}
+	return fmt.Sprintf("%s (%s)", ec.ServedModel(), ec.Mode())
+}
 
-	// Append metrics
-	t.AppendRow(table.Row{"Model", metrics.Model})
-	t.AppendRow(table.Row{"Concurrency", metrics.Concurrency})
-	t.AppendRow(table.Row{"Total Time (s)", fmt.Sprintf("%.2f", metrics.TotalTimeSec)})
-	t.AppendRow(table.Row{"Number of Requests", metrics.NumRequests})
-	t.AppendRow(table.Row{"Request Throughput (req/s)", fmt.Sprintf("%.2f", metrics.RequestThroughput)})
-	t.AppendRow(table.Row{"Avg TTFT (ms)", fmt.Sprintf("%.2f", metrics.AvgTTFTMs)})
-	t.AppendRow(table.Row{"Avg Inter Token Latency (ms)", fmt.Sprintf("%.2f", metrics.AvgITLMs)})
-	t.AppendRow(table.Row{"Avg Request Latency (ms)", fmt.Sprintf("%.2f", metrics.AvgRequestLatencyMs)})
-	t.AppendRow(table.Row{"Total Output Tokens", metrics.TotalOutputTokens})
-	t.AppendRow(table.Row{"Output Token Throughput (tokens/s)", fmt.Sprintf("%.2f", metrics.OutputTokenThroughput)})
+// loadHeader returns the header of the load column: Concurrency unless some experiments were run at a request rate
+func loadHeader(emp input.ExpMetricPair) string {
+	for ec := range emp {
+		if ec.LoadMode() == input.LoadRequestRate {
+			return "Load"
+		}
+	}
+	return "Concurrency"
+}
 
-	// Render the table
-	fmt.Println("GenAI Perf Metrics:")
-	t.Render()
-	fmt.Println()
+// loadCell returns the load of the experiment: its concurrency, or its offered and achieved request rates
+func loadCell(ec input.GenAIPerfExpConf, m input.ExpMetrics) string {
+	if ec.LoadMode() == input.LoadConcurrency {
+		return fmt.Sprint(ec.Concurrency)
+	}
+	return fmt.Sprintf("%s (%.2f achieved)", ec.LoadDesc(), m.PerfM.AchievedRequestRate)
 }
 
-func PowerMetricsToTableOut(metrics input.KeplerPowerMetrics) {
-	// Create a table
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Metric", "Value"})
+// requestsCell returns the requests of the experiment, out of the expected ones when it is still running
+func requestsCell(ec input.GenAIPerfExpConf, m input.ExpMetrics) string {
+	if !m.Partial {
+		return fmt.Sprint(m.PerfM.NumRequests)
+	}
+	return fmt.Sprintf("%d/%d (partial)", m.PerfM.NumRequests, ec.RunCount)
+}
 
-	// Append power metrics
-	t.AppendRow(table.Row{"Node Platform (J)", fmt.Sprintf("%.2f", metrics.NodePlatformJ)})
-	t.AppendRow(table.Row{"Node GPU (J)", fmt.Sprintf("%.2f", metrics.NodeGPUJ)})
-	t.AppendRow(table.Row{"Node Package (J)", fmt.Sprintf("%.2f", metrics.NodePackageJ)})
-	t.AppendRow(table.Row{"Node DRAM (J)", fmt.Sprintf("%.2f", metrics.NodeDRAMJ)})
-	t.AppendRow(table.Row{"Node Other (J)", fmt.Sprintf("%.2f", metrics.NodeOtherJ)})
-	t.AppendRow(table.Row{"Pod Platform (J)", fmt.Sprintf("%.2f", metrics.PodPlatformJ)})
-	t.AppendRow(table.Row{"Pod GPU (J)", fmt.Sprintf("%.2f", metrics.PodGPUJ)})
-	t.AppendRow(table.Row{"Pod Package (J)", fmt.Sprintf("%.2f", metrics.PodPackageJ)})
-	t.AppendRow(table.Row{"Pod DRAM (J)", fmt.Sprintf("%.2f", metrics.PodDRAMJ)})
-	t.AppendRow(table.Row{"Pod Other (J)", fmt.Sprintf("%.2f", metrics.PodOtherJ)})
+// repsCell returns the repetitions the experiment was aggregated from, out of the expected ones when some are missing
+func repsCell(m input.ExpMetrics) string {
+	if m.Repetitions() == m.ExpectedRepetitions() {
+		return fmt.Sprint(m.Repetitions())
+	}
+	return fmt.Sprintf("%d/%d", m.Repetitions(), m.ExpectedRepetitions())
+}
 
-	// Render the table
-	fmt.Println("Power Metrics:")
-	t.Render()
-	fmt.Println()
+// withCI formats value of the experiment, followed by its 95% confidence interval when it was repeated
+func withCI(m input.ExpMetrics, format string, value func(input.ExpMetrics) float64) string {
+	if math.IsNaN(value(m)) {
+		return "N/A"
+	}
+	if m.Repetitions() < 2 {
+		return fmt.Sprintf(format, value(m))
+	}
+	return fmt.Sprintf(format+" ± "+format, value(m), m.Stats(value).CI95)
+}
+
+// Exporter prints the experiment metrics as a table on stdout
+type Exporter struct{}
+
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+func (e *Exporter) Name() string {
+	return "table"
+}
+
+func (e *Exporter) Export(emp input.ExpMetricPair) error {
+	ExpMetricsToTableOut(emp)
+	return nil
 }
@@ -0,0 +1,233 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// While an experiment runs, the requests completed so far can be written to checkpoint files next to
+// its profile export and in the same format, named after the profile by itpe_report.checkpoint_pattern,
+// e.g. 10_4_profile.checkpoint.json or 10_4_profile.checkpoint-3.json for 10_4_profile.json by default.
+// Once the experiment completes, its profile supersedes the checkpoints.
+
+// profilePlaceholder stands for the profile name without profileSuffix in the checkpoint pattern
+const profilePlaceholder = "{profile}"
+
+// checkpointRe matches the checkpoint file names, capturing the profile name, see SetCheckpointPattern
+var checkpointRe = func() *regexp.Regexp {
+	re, err := compileCheckpointPattern(config.DefaultCheckpointPattern)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}()
+
+// SetCheckpointPattern sets the file name pattern of the checkpoints, in which {profile} stands for
+// the profile name without _profile.json and * for any text
+func SetCheckpointPattern(pattern string) error {
+	re, err := compileCheckpointPattern(pattern)
+	if err != nil {
+		return err
+	}
+	checkpointRe = re
+	return nil
+}
+
+// compileCheckpointPattern converts a checkpoint pattern into a regexp capturing the profile name
+func compileCheckpointPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.Count(pattern, profilePlaceholder) != 1 {
+		return nil, fmt.Errorf("invalid checkpoint pattern %q: must hold %s once", pattern, profilePlaceholder)
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return nil, fmt.Errorf("invalid checkpoint pattern %q: must be a file name", pattern)
+	}
+	glob := func(s string) string {
+		parts := strings.Split(s, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		return strings.Join(parts, ".*")
+	}
+	before, after, _ := strings.Cut(pattern, profilePlaceholder)
+	// Profile names are <runCount>_<load>, e.g. 10_4 or 10_2.5, so that a wildcard can follow them
+	re, err := regexp.Compile("^" + glob(before) + `(\d+_\d+(?:\.\d+)?)` + glob(after) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint pattern %q: %v", pattern, err)
+	}
+	return re, nil
+}
+
+// isCheckpoint tells whether path is a checkpoint file
+func isCheckpoint(path string) bool {
+	name := filepath.Base(path)
+	// A profile is never a checkpoint, whatever the pattern
+	return !strings.HasSuffix(name, profileSuffix) && checkpointRe.MatchString(name)
+}
+
+// profileOf returns the path of the profile export a checkpoint is written for, path itself for a profile
+func profileOf(path string) string {
+	if !isCheckpoint(path) {
+		return path
+	}
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, checkpointRe.FindStringSubmatch(name)[1]+profileSuffix)
+}
+
+// latestCheckpoint returns the most recently written checkpoint of the profile at path, empty if there is none
+func latestCheckpoint(path string) (string, error) {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestMod time.Time
+	for _, e := range entries {
+		ckpt := filepath.Join(dir, e.Name())
+		if !e.Type().IsRegular() || !isCheckpoint(ckpt) || profileOf(ckpt) != path {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		// Ties are broken by name so that numbered checkpoints written at once pick the last one
+		if latest == "" || info.ModTime().After(latestMod) || (info.ModTime().Equal(latestMod) && ckpt > latest) {
+			latest, latestMod = ckpt, info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// resolveProfile returns the file to read the experiment of the profile at path from: the profile itself
+// when it exists, otherwise its latest checkpoint with checkpoint, which makes the experiment partial
+func resolveProfile(path string, checkpoint bool) (file string, partial bool, err error) {
+	if _, err := os.Stat(path); err == nil || !checkpoint {
+		return path, false, err
+	}
+	ckpt, err := latestCheckpoint(path)
+	if err != nil {
+		return "", false, err
+	}
+	if ckpt == "" {
+		return "", false, fmt.Errorf("no profile nor checkpoint for %s", path)
+	}
+	return ckpt, true, nil
+}
+
+// discoverCheckpoints returns the latest checkpoint of every experiment under dir whose profile is not in profiles, sorted
+func discoverCheckpoints(dir string, profiles []string) ([]string, error) {
+	completed := make(map[string]bool)
+	for _, p := range profiles {
+		completed[p] = true
+	}
+	seen := make(map[string]bool)
+	var ckpts []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !isCheckpoint(path) {
+			return nil
+		}
+		profile := profileOf(path)
+		if completed[profile] || seen[profile] {
+			return nil
+		}
+		seen[profile] = true
+		ckpt, err := latestCheckpoint(profile)
+		if err != nil {
+			return err
+		}
+		ckpts = append(ckpts, ckpt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking artifacts directory %s: %v", dir, err)
+	}
+	return ckpts, nil
+}
+
+// PendingExperiments counts the experiments still running: those of the sweep described by the config
+// without a completed profile under the artifacts directory, or the partial ones of emp without a sweep
+func PendingExperiments(c config.Config, emp ExpMetricPair) (int, error) {
+	if len(c.GenAIPerf.Models) == 0 {
+		pending := 0
+		for _, em := range emp {
+			if em.Partial {
+				pending++
+			}
+		}
+		return pending, nil
+	}
+
+	paths, err := DiscoverProfiles(c.ReportConf.ArtfDir)
+	if err != nil {
+		return 0, err
+	}
+	found := make(map[GenAIPerfExpConf]bool)
+	for _, path := range paths {
+		if ec, err := GetConfFromPath(path); err == nil {
+			found[ec] = true
+		}
+	}
+	missing, err := missingCells(c, found)
+	if err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// ArtifactsState fingerprints the name, size and modification time of the profiles and checkpoints under dir
+func ArtifactsState(dir string) (uint64, error) {
+	h := fnv.New64a()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || (!strings.HasSuffix(path, profileSuffix) && !isCheckpoint(path)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Removed since listed
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("walking artifacts directory %s: %v", dir, err)
+	}
+	return h.Sum64(), nil
+}
+
+// WaitForChanges polls dir every interval until its state differs from state, i.e. until a profile
+// or a checkpoint is written or removed, or ctx is done
+func WaitForChanges(ctx context.Context, dir string, state uint64, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		cur, err := ArtifactsState(dir)
+		if err != nil {
+			return err
+		}
+		if cur != state {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package input
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+func TestCheckpointPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    string // Profile the checkpoint is written for, empty if name is no checkpoint
+	}{
+		{config.DefaultCheckpointPattern, "10_4_profile.checkpoint.json", "10_4_profile.json"},
+		{config.DefaultCheckpointPattern, "10_4_profile.checkpoint-3.json", "10_4_profile.json"},
+		{config.DefaultCheckpointPattern, "10_2.5_profile.checkpoint-12.json", "10_2.5_profile.json"},
+		{config.DefaultCheckpointPattern, "10_4_profile.json", ""},
+		{config.DefaultCheckpointPattern, "10_4_profile.checkpoint.csv", ""},
+		{"{profile}.partial-*.json", "10_4.partial-7.json", "10_4_profile.json"},
+		{"{profile}.partial-*.json", "10_4_profile.checkpoint.json", ""},
+		{"ckpt_{profile}.json", "ckpt_10_4.json", "10_4_profile.json"},
+		// Profiles are never checkpoints, even when matching the pattern
+		{"{profile}*.json", "10_4_profile.json", ""},
+		{"{profile}*.json", "10_4.tmp.json", "10_4_profile.json"},
+	}
+	t.Cleanup(func() { SetCheckpointPattern(config.DefaultCheckpointPattern) })
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if err := SetCheckpointPattern(tt.pattern); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("exp", tt.name)
+			if got := isCheckpoint(path); got != (tt.want != "") {
+				t.Errorf("isCheckpoint(%q) = %v, want %v", path, got, tt.want != "")
+			}
+			want := path
+			if tt.want != "" {
+				want = filepath.Join("exp", tt.want)
+			}
+			if got := profileOf(path); got != want {
+				t.Errorf("profileOf(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
+
+func TestCheckpointPatternInvalid(t *testing.T) {
+	for _, pattern := range []string{"", "checkpoint.json", "{profile}-{profile}.json", "ckpt/{profile}.json"} {
+		if err := SetCheckpointPattern(pattern); err == nil {
+			t.Errorf("SetCheckpointPattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+// copyFixture copies the checkpoint fixture into a temporary directory, renaming the checkpoints with rename.
+// The checkpoints are written a second apart in the order of their names.
+func copyFixture(t *testing.T, rename func(name string) string) string {
+	t.Helper()
+	src := filepath.Join("testdata", "checkpoint")
+	dst := t.TempDir()
+	modTime := time.Now().Add(-time.Hour)
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if isCheckpoint(path) {
+			target = filepath.Join(filepath.Dir(target), rename(d.Name()))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+		// Walked in lexical order
+		modTime = modTime.Add(time.Second)
+		return os.Chtimes(target, modTime, modTime)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+// checkpointConf returns the config of the sweep of the checkpoint fixture under dir
+func checkpointConf(dir string) config.Config {
+	c := *config.DefaultConfig()
+	c.ReportConf.ArtfDir = dir
+	c.ReportConf.EnergySource = config.EnergySourceConf{Type: config.EnergySourceCSV, Path: filepath.Join(dir, "power.csv")}
+	c.GenAIPerf.Enabled.Stream = true
+	c.GenAIPerf.Enabled.Checkpoint = true
+	c.GenAIPerf.Models = []string{"mistral"}
+	c.GenAIPerf.Concurrency = []int{2, 4}
+	c.GenAIPerf.Requests.RunCount = []int{4}
+	c.GenAIPerf.TokenConfs.Input = []config.TokenConf{{Name: "S", Mean: 150}}
+	c.GenAIPerf.TokenConfs.Output = []config.TokenConf{{Name: "S", Mean: 50}}
+	return c
+}
+
+func TestGenExpMetricPairCheckpoint(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Cleanup(func() { SetCheckpointPattern(config.DefaultCheckpointPattern) })
+
+	tests := []struct {
+		name    string
+		pattern string
+		rename  func(string) string
+	}{
+		{"default pattern", config.DefaultCheckpointPattern, func(name string) string { return name }},
+		{"custom pattern", "{profile}.partial-*.json", func(name string) string {
+			return strings.Replace(name, "_profile.checkpoint", ".partial", 1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetCheckpointPattern(config.DefaultCheckpointPattern); err != nil {
+				t.Fatal(err)
+			}
+			dir := copyFixture(t, tt.rename)
+			if err := SetCheckpointPattern(tt.pattern); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, discover := range []bool{false, true} {
+				c := checkpointConf(dir)
+				c.ReportConf.Discover = discover
+				emp, err := GenExpMetricPair(context.Background(), c, logger)
+				if err != nil {
+					t.Fatalf("GenExpMetricPair (discover %v) failed: %v", discover, err)
+				}
+				if len(emp) != 2 {
+					t.Fatalf("%d experiments (discover %v), want 2", len(emp), discover)
+				}
+				for ec, em := range emp {
+					// The running experiment is read from its latest checkpoint
+					wantPartial, wantRequests := ec.Concurrency == 4, 4
+					if wantPartial {
+						wantRequests = 3
+					}
+					if em.Partial != wantPartial || em.PerfM.NumRequests != wantRequests {
+						t.Errorf("concurrency %d (discover %v): partial %v with %d requests, want %v with %d",
+							ec.Concurrency, discover, em.Partial, em.PerfM.NumRequests, wantPartial, wantRequests)
+					}
+					if ec.RunCount != 4 || ec.Model != "mistral" || ec.InputMean != 150 || ec.OutputMean != 50 {
+						t.Errorf("experiment config %+v (discover %v), want the one of the sweep", ec, discover)
+					}
+					if math.IsNaN(em.PowerM.NodePlatformJ) || em.PowerM.NodePlatformJ <= 0 {
+						t.Errorf("concurrency %d (discover %v): node energy %v, want the one of the power log",
+							ec.Concurrency, discover, em.PowerM.NodePlatformJ)
+					}
+				}
+
+				pending, err := PendingExperiments(c, emp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pending != 1 {
+					t.Errorf("%d pending experiments (discover %v), want 1", pending, discover)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The profile export is decoded token by token so that only one request is held in the decoder at a time
+// and the response bodies, by far the largest part of the file for long outputs, are summarized then dropped.
+
+// DecodeProfile decodes a GenAI-Perf profile export from r.
+// The response bodies of the requests are dropped, only their token usage
+// and the tokens of their text and of the prompt counted with tok (an estimate when nil) are kept.
+func DecodeProfile(r io.Reader, tok Tokenizer) (*ProfileExport, error) {
+	if tok == nil {
+		tok = approxTokenizer{}
+	}
+	dec := json.NewDecoder(r)
+	var profile ProfileExport
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "experiments":
+			return decodeArray(dec, func() error {
+				exp, err := decodeExperiment(dec, tok)
+				if err != nil {
+					return err
+				}
+				profile.Experiments = append(profile.Experiments, exp)
+				return nil
+			})
+		case "version":
+			return dec.Decode(&profile.Version)
+		case "service_kind":
+			return dec.Decode(&profile.ServiceKind)
+		case "endpoint":
+			return dec.Decode(&profile.Endpoint)
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func decodeExperiment(dec *json.Decoder, tok Tokenizer) (Experiment, error) {
+	var exp Experiment
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "experiment":
+			return dec.Decode(&exp.Experiment)
+		case "window_boundaries":
+			return dec.Decode(&exp.WindowBoundaries)
+		case "requests":
+			return decodeArray(dec, func() error {
+				req, err := decodeRequest(dec, tok)
+				if err != nil {
+					return fmt.Errorf("request %d: %v", len(exp.Requests), err)
+				}
+				exp.Requests = append(exp.Requests, req)
+				return nil
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	return exp, err
+}
+
+func decodeRequest(dec *json.Decoder, tok Tokenizer) (Request, error) {
+	var req Request
+	var output strings.Builder // Generated text, counted once the chunks are joined
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "timestamp":
+			return dec.Decode(&req.Timestamp)
+		case "request_inputs":
+			return dec.Decode(&req.RequestInputs)
+		case "response_timestamps":
+			return dec.Decode(&req.ResponseTimestamps)
+		case "response_outputs":
+			return decodeArray(dec, func() error {
+				var out ResponseOutput
+				if err := dec.Decode(&out); err != nil {
+					return err
+				}
+				text, usage := parseResponse(out.Response)
+				output.WriteString(text)
+				if usage != nil {
+					req.Usage = usage
+				}
+				return nil
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	req.InputTokens = tok.CountTokens(promptText(req.RequestInputs.Payload))
+	req.OutputTokens = tok.CountTokens(output.String())
+	return req, err
+}
+
+// decodeObject calls field with the key of every member of the next object, which must consume its value
+func decodeObject(dec *json.Decoder, field func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected %v instead of an object key", tok)
+		}
+		if err := field(key); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeArray calls elem for every element of the next array, which must consume it.
+// A null array has no element.
+func decodeArray(dec *json.Decoder, elem func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("unexpected %v instead of an array", tok)
+	}
+	for dec.More() {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("unexpected %v instead of %v", tok, delim)
+	}
+	return nil
+}
+
+// skipValue consumes the next value without keeping it
+func skipValue(dec *json.Decoder) error {
+	return dec.Decode(&discard{})
+}
+
+// discard is decoded from any value without allocating it
+type discard struct{}
+
+func (*discard) UnmarshalJSON([]byte) error { return nil }
@@ -0,0 +1,209 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// unmarshaledProfile is the profile export as decoded whole, response bodies included
+type unmarshaledProfile struct {
+	Experiments []struct {
+		Requests []struct {
+			Timestamp     int64 `json:"timestamp"`
+			RequestInputs struct {
+				Payload string `json:"payload"`
+			} `json:"request_inputs"`
+			ResponseTimestamps []int64          `json:"response_timestamps"`
+			ResponseOutputs    []ResponseOutput `json:"response_outputs"`
+		} `json:"requests"`
+	} `json:"experiments"`
+}
+
+// syntheticProfile generates a streamed profile export of requests answered in chunks
+func syntheticProfile(requests, chunks int) []byte {
+	const begin = int64(1_700_000_000_000_000_000)
+	chunk := `data: {"id":"chatcmpl-%d","object":"chat.completion.chunk","model":"llama3.2:1b","choices":[{"index":0,"delta":{"content":" token%d"}}]}` + "\n\n"
+	payload, _ := json.Marshal(map[string]any{
+		"model":    "llama3.2:1b",
+		"stream":   true,
+		"messages": []map[string]string{{"role": "user", "content": strings.Repeat("hi ", 500)}},
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"experiments":[{"experiment":{"mode":"concurrency","value":4},"requests":[`)
+	for i := 0; i < requests; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		start := begin + int64(i)*1e9
+		timestamps := make([]int64, chunks)
+		outputs := make([]ResponseOutput, chunks)
+		for k := range timestamps {
+			timestamps[k] = start + 5e7 + int64(k)*2e7
+			outputs[k] = ResponseOutput{Response: fmt.Sprintf(chunk, i, k)}
+		}
+		req, _ := json.Marshal(map[string]any{
+			"timestamp":           start,
+			"request_inputs":      map[string]string{"payload": string(payload)},
+			"response_timestamps": timestamps,
+			"response_outputs":    outputs,
+		})
+		buf.Write(req)
+	}
+	buf.WriteString(`],"window_boundaries":[]}],"version":"0.0.1","service_kind":"openai","endpoint":"v1/chat/completions"}`)
+	return buf.Bytes()
+}
+
+// unmarshalProfile decodes the whole profile with json.Unmarshal then counts the tokens, as DecodeProfile does
+func unmarshalProfile(data []byte, tok Tokenizer) ([]Request, error) {
+	var up unmarshaledProfile
+	if err := json.Unmarshal(data, &up); err != nil {
+		return nil, err
+	}
+	var reqs []Request
+	for _, r := range up.Experiments[0].Requests {
+		req := Request{Timestamp: r.Timestamp, ResponseTimestamps: r.ResponseTimestamps}
+		req.RequestInputs.Payload = r.RequestInputs.Payload
+		var output strings.Builder
+		for _, out := range r.ResponseOutputs {
+			text, usage := parseResponse(out.Response)
+			output.WriteString(text)
+			if usage != nil {
+				req.Usage = usage
+			}
+		}
+		req.InputTokens = tok.CountTokens(promptText(req.RequestInputs.Payload))
+		req.OutputTokens = tok.CountTokens(output.String())
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func TestDecodeProfile(t *testing.T) {
+	data := syntheticProfile(3, 10)
+	profile, err := DecodeProfile(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("DecodeProfile failed: %v", err)
+	}
+	if len(profile.Experiments) != 1 || profile.Endpoint != "v1/chat/completions" {
+		t.Fatalf("DecodeProfile = %+v, want a single experiment of v1/chat/completions", profile)
+	}
+	exp := profile.Experiments[0]
+	if exp.Experiment.Mode != LoadConcurrency || exp.Experiment.Value != 4 {
+		t.Errorf("experiment = %+v, want concurrency 4", exp.Experiment)
+	}
+
+	want, err := unmarshalProfile(data, approxTokenizer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.Requests) != len(want) {
+		t.Fatalf("%d requests decoded, want %d", len(exp.Requests), len(want))
+	}
+	for i, req := range exp.Requests {
+		w := want[i]
+		if req.Timestamp != w.Timestamp || len(req.ResponseTimestamps) != len(w.ResponseTimestamps) ||
+			req.InputTokens != w.InputTokens || req.OutputTokens != w.OutputTokens {
+			t.Errorf("request %d = %+v, want %+v", i, req, w)
+		}
+		if req.OutputTokens == 0 || req.InputTokens == 0 {
+			t.Errorf("request %d counted %d input and %d output tokens, want some", i, req.InputTokens, req.OutputTokens)
+		}
+	}
+}
+
+func TestDecodeProfileInvalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`[]`,
+		`{"experiments":{}}`,
+		`{"experiments":[{"requests":[{"timestamp":"now"}]}]}`,
+		`{"experiments":[{"requests":[`,
+	} {
+		if _, err := DecodeProfile(strings.NewReader(data), nil); err == nil {
+			t.Errorf("DecodeProfile(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+// benchProfile is the large profile of the benchmarks, 200 requests of 1000 chunks
+var benchProfile = sync.OnceValue(func() []byte { return syntheticProfile(200, 1000) })
+
+// benchmarkDecode runs decode over the large profile and reports, next to the allocations,
+// the heap still held by the decoded profile (retained-B/op)
+func benchmarkDecode(b *testing.B, decode func(data []byte) (any, error)) {
+	data := benchProfile()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	var decoded any
+	for i := 0; i < b.N; i++ {
+		var err error
+		if decoded, err = decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(decoded)
+	b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "retained-B/op")
+}
+
+func BenchmarkDecodeProfile(b *testing.B) {
+	benchmarkDecode(b, func(data []byte) (any, error) {
+		return DecodeProfile(bytes.NewReader(data), approxTokenizer{})
+	})
+}
+
+// BenchmarkUnmarshalProfile is the baseline of BenchmarkDecodeProfile,
+// decoding the whole profile with json.Unmarshal and keeping the response bodies
+func BenchmarkUnmarshalProfile(b *testing.B) {
+	benchmarkDecode(b, func(data []byte) (any, error) {
+		var up unmarshaledProfile
+		err := json.Unmarshal(data, &up)
+		return &up, err
+	})
+}
+
+func TestDecodeProfileStreamedTokens(t *testing.T) {
+	// The same answer whole and in chunks much shorter than a token of the approximation
+	const answer = "The capital of France is Paris, known for the Eiffel Tower."
+	profile := func(outputs []string) string {
+		var outs []ResponseOutput
+		for _, o := range outputs {
+			outs = append(outs, ResponseOutput{Response: o})
+		}
+		data, _ := json.Marshal(map[string]any{"experiments": []map[string]any{{
+			"requests": []map[string]any{{"timestamp": 1, "response_outputs": outs}},
+		}}})
+		return string(data)
+	}
+	whole := profile([]string{fmt.Sprintf(`{"choices":[{"message":{"content":%q}}]}`, answer)})
+	var chunks []string
+	for _, c := range strings.SplitAfter(answer, " ") {
+		chunks = append(chunks, fmt.Sprintf(`data: {"choices":[{"delta":{"content":%q}}]}`+"\n\n", c))
+	}
+	streamed := profile(append(chunks, "data: [DONE]\n\n"))
+
+	for _, tok := range []Tokenizer{approxTokenizer{}, wordTokenizer{}} {
+		want := tok.CountTokens(answer)
+		for name, data := range map[string]string{"whole": whole, "streamed": streamed} {
+			p, err := DecodeProfile(strings.NewReader(data), tok)
+			if err != nil {
+				t.Fatalf("DecodeProfile of the %s response failed: %v", name, err)
+			}
+			if got := p.Experiments[0].Requests[0].OutputTokens; got != want {
+				t.Errorf("%T: %s response counted %d output tokens, want %d", tok, name, got, want)
+			}
+		}
+	}
+}
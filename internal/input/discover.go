@@ -0,0 +1,186 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// profileSuffix is the file name suffix of the GenAI-Perf profile exports
+const profileSuffix = "_profile.json"
+
+// DiscoverProfiles walks dir and returns the path of every GenAI-Perf profile export found, sorted
+func DiscoverProfiles(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("artifacts directory is empty")
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && strings.HasSuffix(d.Name(), profileSuffix) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking artifacts directory %s: %v", dir, err)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// checkProfile makes sure the profile holds an experiment with answered requests
+func checkProfile(profile *ProfileExport) error {
+	if len(profile.Experiments) == 0 {
+		return fmt.Errorf("no experiment in profile")
+	}
+	reqs := profile.Experiments[0].Requests
+	if len(reqs) == 0 {
+		return fmt.Errorf("no request in experiment")
+	}
+	if len(reqs[len(reqs)-1].ResponseTimestamps) == 0 {
+		return fmt.Errorf("last request has no response")
+	}
+	return nil
+}
+
+// discoverConf derives the experiment config of a discovered profile.
+// The token lengths come from the directory layout, while the served model and
+// the load (concurrency or request rate) are taken from the profile when available since they are what actually ran.
+// Outside of the sweep layout, the whole config is derived from the profile with payloadConf.
+func discoverConf(path string, profile *ProfileExport, logger *slog.Logger) (GenAIPerfExpConf, error) {
+	exp := profile.Experiments[0]
+	ec, err := GetConfFromPath(path)
+	if err != nil {
+		pec, perr := payloadConf(exp)
+		if perr != nil {
+			return ec, fmt.Errorf("%v, nor from the profile: %v", err, perr)
+		}
+		logger.Debug("Directory is not of the sweep layout, using the profile", "file", path, "error", err)
+		ec = pec
+	}
+
+	if value := exp.Experiment.Value; value > 0 {
+		ran := ec
+		switch exp.Experiment.Mode {
+		case LoadConcurrency:
+			ran.Concurrency, ran.RequestRate = int(value), 0
+		case LoadRequestRate:
+			ran.Concurrency, ran.RequestRate = 0, value
+		}
+		if ran != ec {
+			logger.Warn("Load of the directory differs from the profile, using the profile one",
+				"file", path, "directory", ec.LoadDesc(), "profile", ran.LoadDesc())
+			ec = ran
+		}
+	}
+	if ec.RunCount == 0 {
+		ec.RunCount = len(exp.Requests)
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal([]byte(exp.Requests[0].RequestInputs.Payload), &payload); err == nil && payload.Model != "" {
+		if dirModel := ec.ServedModel(); payload.Model != dirModel {
+			mi, err := ParseModel(payload.Model)
+			if err != nil {
+				logger.Warn("Cannot parse the model of the profile, using the directory one",
+					"file", path, "directory", dirModel, "profile", payload.Model, "error", err)
+			} else {
+				logger.Warn("Model of the directory differs from the profile, using the profile one",
+					"file", path, "directory", dirModel, "profile", payload.Model)
+				ec.setModel(mi)
+			}
+		}
+	}
+
+	return ec, nil
+}
+
+// payloadConf derives the experiment config from the profile alone: the served model and the output length
+// (max_tokens, max_completion_tokens or Ollama's num_predict) from the payload, the input length from the mean
+// prompt tokens of the requests and the load from the experiment
+func payloadConf(exp Experiment) (GenAIPerfExpConf, error) {
+	var ec GenAIPerfExpConf
+	var payload struct {
+		Model               string `json:"model"`
+		MaxTokens           int    `json:"max_tokens"`
+		MaxCompletionTokens int    `json:"max_completion_tokens"`
+		Options             struct {
+			NumPredict int `json:"num_predict"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(exp.Requests[0].RequestInputs.Payload), &payload); err != nil {
+		return ec, fmt.Errorf("parsing payload: %v", err)
+	}
+	if payload.Model == "" {
+		return ec, fmt.Errorf("no model in payload")
+	}
+	mi, err := ParseModel(payload.Model)
+	if err != nil {
+		return ec, err
+	}
+	ec.setModel(mi)
+
+	for _, n := range []int{payload.MaxTokens, payload.MaxCompletionTokens, payload.Options.NumPredict} {
+		if n > 0 {
+			ec.OutputMean = n
+			break
+		}
+	}
+	if ec.OutputMean == 0 {
+		return ec, fmt.Errorf("no output length in payload")
+	}
+
+	var inputTokens int
+	for _, req := range exp.Requests {
+		inputTokens += req.inputTokens()
+	}
+	ec.InputMean = int(math.Round(float64(inputTokens) / float64(len(exp.Requests))))
+	if ec.InputMean == 0 {
+		return ec, fmt.Errorf("no prompt in payload")
+	}
+
+	value := exp.Experiment.Value
+	switch {
+	case value > 0 && exp.Experiment.Mode == LoadConcurrency:
+		ec.Concurrency = int(value)
+	case value > 0 && exp.Experiment.Mode == LoadRequestRate:
+		ec.RequestRate = value
+	default:
+		return ec, fmt.Errorf("no load in profile")
+	}
+	return ec, nil
+}
+
+// missingCells returns the experiments expected from the config that were not discovered
+func missingCells(c config.Config, found map[GenAIPerfExpConf]bool) ([]GenAIPerfExpConf, error) {
+	paths, err := GenJSONPaths(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []GenAIPerfExpConf
+	for _, path := range paths {
+		ec, err := GetConfFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if !found[ec] {
+			missing = append(missing, ec)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,37 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// EnergySource provides the energy consumed by the node and the pod serving model within a time window.
+// Components a source cannot measure are left as 0, components whose measurement failed are NaN
+// and returned along with an error.
+type EnergySource interface {
+	Energy(ctx context.Context, model string, begin, end time.Time) (KeplerPowerMetrics, error)
+}
+
+// NewEnergySource creates the energy source selected in the report config
+func NewEnergySource(ctx context.Context, c config.Config, logger *slog.Logger) (EnergySource, error) {
+	esc := c.ReportConf.EnergySource
+	switch esc.Type {
+	case "", config.EnergySourceKepler:
+		return newKeplerSource(ctx, c.ReportConf, logger)
+	case config.EnergySourceRAPL:
+		return newRAPLSource(esc.Path)
+	case config.EnergySourceCSV:
+		return newPowerLogSource(esc.Path, esc.Columns)
+	default:
+		return nil, fmt.Errorf("unknown energy source type: %s", esc.Type)
+	}
+}
+
+// GetPowerMetrics computes the energy consumed by model within the window of an experiment from the given source
+func GetPowerMetrics(ctx context.Context, win SteadyWindow, model string, source EnergySource) (KeplerPowerMetrics, error) {
+	return source.Energy(ctx, model, win.Begin, win.End)
+}
@@ -0,0 +1,184 @@
+package input
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// Footprint is the carbon emissions and the electricity cost of the node platform energy of an experiment.
+// The carbon is NaN without a grid carbon intensity and the cost is NaN without an electricity price.
+type Footprint struct {
+	FacilityKWh   float64 `json:"facility_kwh"`             // Node platform energy scaled by the PUE
+	GridIntensity float64 `json:"grid_intensity_g_per_kwh"` // Mean grid carbon intensity over the experiment
+	CarbonG       float64 `json:"carbon_g"`                 // unit: gCO2e
+	Cost          float64 `json:"cost"`                     // unit: configured currency
+}
+
+// intensitySample is a grid carbon intensity reading (unit: gCO2e/kWh)
+type intensitySample struct {
+	Time  time.Time
+	Value float64
+}
+
+// nanFootprint returns a footprint with every value missing
+func nanFootprint() Footprint {
+	return Footprint{FacilityKWh: math.NaN(), GridIntensity: math.NaN(), CarbonG: math.NaN(), Cost: math.NaN()}
+}
+
+// loadCarbonIntensity reads a grid carbon intensity log, sorted by time
+func loadCarbonIntensity(conf config.CarbonIntensityCSVConf) ([]intensitySample, error) {
+	f, err := os.Open(conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening carbon intensity log %s: %v", conf.Path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading carbon intensity log header from %s: %v", conf.Path, err)
+	}
+	tsCol, valueCol := -1, -1
+	for i, h := range header {
+		switch strings.TrimSpace(h) {
+		case conf.TimestampColumn:
+			tsCol = i
+		case conf.Column:
+			valueCol = i
+		}
+	}
+	if tsCol < 0 || valueCol < 0 {
+		return nil, fmt.Errorf("carbon intensity log %s is missing column %s or %s", conf.Path, conf.TimestampColumn, conf.Column)
+	}
+
+	var samples []intensitySample
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading carbon intensity log %s: %v", conf.Path, err)
+		}
+		ts, err := parseTimestamp(record[tsCol])
+		if err != nil {
+			return nil, fmt.Errorf("parsing carbon intensity log %s: %v", conf.Path, err)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[valueCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s in carbon intensity log %s: %v", conf.Column, conf.Path, err)
+		}
+		samples = append(samples, intensitySample{Time: ts, Value: value})
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("carbon intensity log %s has no reading", conf.Path)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
+
+// meanIntensity averages the readings over [begin, end], each reading holding until the next one.
+// It returns NaN when the window starts before the first reading.
+func meanIntensity(samples []intensitySample, begin, end time.Time) float64 {
+	if len(samples) == 0 || begin.Before(samples[0].Time) {
+		return math.NaN()
+	}
+	if !end.After(begin) {
+		i := sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(begin) })
+		return samples[i-1].Value
+	}
+
+	var sum float64
+	for i, s := range samples {
+		from := s.Time
+		if from.Before(begin) {
+			from = begin
+		}
+		to := end
+		if i+1 < len(samples) && samples[i+1].Time.Before(end) {
+			to = samples[i+1].Time
+		}
+		if to.After(from) {
+			sum += s.Value * to.Sub(from).Seconds()
+		}
+	}
+	return sum / end.Sub(begin).Seconds()
+}
+
+// newFootprint converts the node platform energy of an experiment given the grid carbon intensity readings,
+// the static intensity is used without readings
+func newFootprint(fc config.FootprintConf, m ExpMetrics, samples []intensitySample) Footprint {
+	fp := nanFootprint()
+	fp.FacilityKWh = m.PowerM.NodePlatformJ * fc.PUE / 3.6e6
+	switch {
+	case len(samples) > 0:
+		fp.GridIntensity = meanIntensity(samples, m.Begin, m.End)
+	case fc.CarbonIntensity > 0:
+		fp.GridIntensity = fc.CarbonIntensity
+	}
+	fp.CarbonG = fp.FacilityKWh * fp.GridIntensity
+	if fc.PricePerKWh > 0 {
+		fp.Cost = fp.FacilityKWh * fc.PricePerKWh
+	}
+	return fp
+}
+
+// applyFootprint sets the carbon emissions and the electricity cost of every experiment
+func applyFootprint(c config.Config, emp ExpMetricPair, logger *slog.Logger) error {
+	fc := c.ReportConf.Footprint
+	if fc.PUE < 1 {
+		return fmt.Errorf("invalid PUE %v, must be >= 1", fc.PUE)
+	}
+
+	var samples []intensitySample
+	if fc.CarbonIntensityCSV.Path != "" {
+		var err error
+		if samples, err = loadCarbonIntensity(fc.CarbonIntensityCSV); err != nil {
+			return err
+		}
+		logger.Info("Loaded grid carbon intensity", "path", fc.CarbonIntensityCSV.Path, "readings", len(samples),
+			"from", samples[0].Time, "to", samples[len(samples)-1].Time)
+	}
+
+	uncovered := 0
+	emp.updateReps(func(_ GenAIPerfExpConf, m *ExpMetrics) {
+		m.Footprint = newFootprint(fc, *m, samples)
+		if len(samples) > 0 && math.IsNaN(m.Footprint.GridIntensity) {
+			uncovered++
+		}
+	})
+	if uncovered > 0 {
+		logger.Warn("Carbon intensity log starts after some experiments, their carbon is unknown", "experiments", uncovered)
+	}
+	return nil
+}
+
+// CarbonPerRequest returns the carbon emissions per request (unit: gCO2e)
+func (m ExpMetrics) CarbonPerRequest() float64 {
+	return m.Footprint.CarbonG / float64(m.PerfM.NumRequests)
+}
+
+// CarbonPerMTokens returns the carbon emissions per million output tokens (unit: gCO2e)
+func (m ExpMetrics) CarbonPerMTokens() float64 {
+	return m.Footprint.CarbonG / float64(m.PerfM.TotalOutputTokens) * 1e6
+}
+
+// CostPerRequest returns the electricity cost per request
+func (m ExpMetrics) CostPerRequest() float64 {
+	return m.Footprint.Cost / float64(m.PerfM.NumRequests)
+}
+
+// CostPerMTokens returns the electricity cost per million output tokens
+func (m ExpMetrics) CostPerMTokens() float64 {
+	return m.Footprint.Cost / float64(m.PerfM.TotalOutputTokens) * 1e6
+}
@@ -1,12 +1,13 @@
 package input
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
-	"strconv"
-	"strings"
+	"path/filepath"
 
 	"github.com/explorerray/itpe-report/config"
 )
@@ -22,8 +23,8 @@ type ProfileExport struct {
 // Experiment contains details about a single experiment
 type Experiment struct {
 	Experiment struct {
-		Mode  string `json:"mode"`
-		Value int    `json:"value"`
+		Mode  string  `json:"mode"`  // LoadConcurrency or LoadRequestRate
+		Value float64 `json:"value"` // Concurrency or request rate
 	} `json:"experiment"`
 	Requests         []Request `json:"requests"`
 	WindowBoundaries []int64   `json:"window_boundaries"`
@@ -36,61 +37,112 @@ type Request struct {
 		Payload string `json:"payload"`
 	} `json:"request_inputs"`
 	ResponseTimestamps []int64 `json:"response_timestamps"`
-	ResponseOutputs    []struct {
-		Response string `json:"response"`
-	} `json:"response_outputs"`
+	// Token counts computed while decoding, the response bodies are not kept
+	InputTokens  int    `json:"-"` // Tokens of the prompt counted by the tokenizer
+	OutputTokens int    `json:"-"` // Tokens of the generated text counted by the tokenizer
+	Usage        *Usage `json:"-"` // Token usage reported by the server, nil if none
+}
+
+// inputTokens returns the prompt tokens reported by the server, or counted by the tokenizer
+func (r Request) inputTokens() int {
+	if r.Usage != nil && r.Usage.PromptTokens > 0 {
+		return r.Usage.PromptTokens
+	}
+	return r.InputTokens
+}
+
+// outputTokens returns the completion tokens reported by the server, or counted by the tokenizer
+func (r Request) outputTokens() int {
+	if r.Usage != nil && r.Usage.CompletionTokens > 0 {
+		return r.Usage.CompletionTokens
+	}
+	return r.OutputTokens
+}
+
+// ResponseOutput is a chunk of a streamed response, or the whole response otherwise
+type ResponseOutput struct {
+	Response string `json:"response"`
 }
 
 // GenAIPerf config for specific experiment
 type GenAIPerfExpConf struct {
-	Model       string
-	PMSize      int // Parameter size (unit: billion parameters)
-	InputMean   int
-	OutputMean  int
-	Concurrency int
-	RunCount    int
+	Model        string  `json:"model"` // Model name without tag
+	Tag          string  `json:"tag"`
+	Family       string  `json:"family"`
+	PMSize       float64 `json:"pm_size"` // Parameter size (unit: billion parameters), 0 if unknown
+	Quantization string  `json:"quantization"`
+	InputMean    int     `json:"input_mean"`
+	OutputMean   int     `json:"output_mean"`
+	Concurrency  int     `json:"concurrency"`  // 0 for request rate experiments
+	RequestRate  float64 `json:"request_rate"` // Offered requests per second, 0 for concurrency experiments
+	RunCount     int     `json:"run_count"`
+	Stream       bool    `json:"stream"` // Whether the responses were streamed
 }
 
 // GenAIPerfMetrics holds computed metrics from the profile
 type GenAIPerfMetrics struct {
-	Model                 string
-	Concurrency           int
-	TotalTimeSec          float64
-	NumRequests           int
-	RequestThroughput     float64
-	AvgTTFTMs             float64
-	AvgRequestLatencyMs   float64
-	AvgITLMs              float64
-	TotalOutputTokens     int
-	OutputTokenThroughput float64
+	Model             string  `json:"served_model"`
+	Concurrency       int     `json:"experiment_concurrency"`
+	TotalTimeSec      float64 `json:"total_time_sec"`
+	NumRequests       int     `json:"num_requests"`
+	RequestThroughput float64 `json:"request_throughput"`
+	// Requests sent per second, as offered by the config and as achieved by the client
+	// (NaN offered rate for concurrency experiments)
+	OfferedRequestRate    float64 `json:"offered_request_rate"`
+	AchievedRequestRate   float64 `json:"achieved_request_rate"`
+	AvgTTFTMs             float64 `json:"avg_ttft_ms"`
+	AvgRequestLatencyMs   float64 `json:"avg_request_latency_ms"`
+	AvgITLMs              float64 `json:"avg_itl_ms"`
+	TotalInputTokens      int     `json:"total_input_tokens"`
+	TotalOutputTokens     int     `json:"total_output_tokens"`
+	TotalTokens           int     `json:"total_tokens"`
+	AvgInputTokens        float64 `json:"avg_input_tokens"` // Per request
+	AvgOutputTokens       float64 `json:"avg_output_tokens"`
+	OutputTokenThroughput float64 `json:"output_token_throughput"`
+	// Distributions over available requests (unit: milliseconds)
+	TTFTStatsMs           DistStats `json:"ttft_ms"`
+	ITLStatsMs            DistStats `json:"itl_ms"`
+	RequestLatencyStatsMs DistStats `json:"request_latency_ms"`
 }
 
-// ParseGenAIPerfJSON reads and parses the profile-export.json file
-func ParseGenAIPerfJSON(filename string, logger *slog.Logger) (*ProfileExport, error) {
-	data, err := os.ReadFile(filename)
+// ParseGenAIPerfJSON reads and parses the profile-export.json file, without keeping the response bodies.
+// tok counts the tokens the server does not report.
+func ParseGenAIPerfJSON(filename string, tok Tokenizer, logger *slog.Logger) (*ProfileExport, error) {
+	f, err := os.Open(filename)
 	if err != nil {
 		logger.Error("Failed to read GenAI-Perf JSON file", "file", filename, "error", err)
 		return nil, fmt.Errorf("reading file %s: %v", filename, err)
 	}
+	defer f.Close()
 
-	var profile ProfileExport
-	if err := json.Unmarshal(data, &profile); err != nil {
+	profile, err := DecodeProfile(bufio.NewReaderSize(f, 1<<20), tok)
+	if err != nil {
 		logger.Error("Failed to parse GenAI-Perf JSON file", "file", filename, "error", err)
 		return nil, fmt.Errorf("parsing JSON from %s: %v", filename, err)
 	}
-
-	return &profile, nil
+	return profile, nil
 }
 
-// ComputeMetrics computes performance metrics from an experiment
-func ComputeMetrics(exp Experiment, ec GenAIPerfExpConf, logger *slog.Logger) GenAIPerfMetrics {
+// ComputeMetrics computes performance metrics from the requests of an experiment within its window
+func ComputeMetrics(exp Experiment, win SteadyWindow, ec GenAIPerfExpConf, logger *slog.Logger) GenAIPerfMetrics {
 	reqs := exp.Requests
 	lastReq := reqs[len(reqs)-1]
 	expBegin := reqs[0].Timestamp
-	expEnd := lastReq.ResponseTimestamps[len(lastReq.ResponseTimestamps)-1]
 	metrics := GenAIPerfMetrics{
-		Concurrency:  exp.Experiment.Value,
-		TotalTimeSec: float64(expEnd-expBegin) / 1e9,
+		Concurrency:         ec.Concurrency,
+		TotalTimeSec:        float64(win.End.Sub(win.Begin)) / 1e9,
+		OfferedRequestRate:  math.NaN(),
+		AchievedRequestRate: math.NaN(),
+	}
+	if exp.Experiment.Mode == LoadConcurrency {
+		metrics.Concurrency = int(exp.Experiment.Value)
+	}
+	if ec.LoadMode() == LoadRequestRate {
+		metrics.OfferedRequestRate = ec.RequestRate
+	}
+	if sendSpan := lastReq.Timestamp - expBegin; len(reqs) > 1 && sendSpan > 0 {
+		// Every request was sent, whether it was answered or not
+		metrics.AchievedRequestRate = float64(len(reqs)-1) / (float64(sendSpan) / 1e9)
 	}
 
 	// Extract model from first payload
@@ -104,30 +156,42 @@ func ComputeMetrics(exp Experiment, ec GenAIPerfExpConf, logger *slog.Logger) Ge
 	}
 
 	var sumTTFT, sumRequestLatency, sumITL float64
-	var totalOutputTokens, numITLIntervals int
-	var availReqNum int
+	var totalInputTokens, totalOutputTokens, numITLIntervals int
+	var ttfts, requestLatencies, itls []float64
+	var availReqNum, measuredReqNum int
 
 	for _, req := range reqs {
-		if len(req.ResponseTimestamps) <= 2 {
-			// the response is not available
+		if responded(req, ec.Stream) {
+			availReqNum++
+		}
+	}
+	for _, req := range win.Requests {
+		if !responded(req, ec.Stream) {
 			continue
 		}
 
-		availReqNum++
+		measuredReqNum++
 		reqBegin := req.Timestamp
 		reqEnd := req.ResponseTimestamps[len(req.ResponseTimestamps)-1]
 
-		// Time to First Token (TTFT) in milliseconds
-		ttft := float64(req.ResponseTimestamps[0]-reqBegin) / 1e6
-		sumTTFT += ttft
-
 		// Request Latency in milliseconds
 		requestLatency := float64(reqEnd-reqBegin) / 1e6
 		sumRequestLatency += requestLatency
+		requestLatencies = append(requestLatencies, requestLatency)
 
-		// Output tokens: count response outputs minus the [DONE] chunk
-		outputTokens := len(req.ResponseOutputs) - 1
-		totalOutputTokens += outputTokens
+		// Tokens reported by the server, or counted from the prompt and the response text
+		totalInputTokens += req.inputTokens()
+		totalOutputTokens += req.outputTokens()
+
+		if !ec.Stream {
+			// The whole response came at once
+			continue
+		}
+
+		// Time to First Token (TTFT) in milliseconds
+		ttft := float64(req.ResponseTimestamps[0]-reqBegin) / 1e6
+		sumTTFT += ttft
+		ttfts = append(ttfts, ttft)
 
 		// Inter-Token Latency (ITL): average time between responses after the first
 		if len(req.ResponseTimestamps) > 1 {
@@ -138,32 +202,71 @@ func ComputeMetrics(exp Experiment, ec GenAIPerfExpConf, logger *slog.Logger) Ge
 			}
 			itl := sumInterval / float64(len(req.ResponseTimestamps)-1)
 			sumITL += itl
+			itls = append(itls, itl)
 			numITLIntervals++
 		}
 	}
 
 	if availReqNum != ec.RunCount {
 		logger.Warn("Number of available requests does not match expected count", "expected", ec.RunCount, "actual", availReqNum,
-			"model", metrics.Model, "input", ec.InputMean, "output", ec.OutputMean, "concurrency", ec.Concurrency)
+			"model", metrics.Model, "input", ec.InputMean, "output", ec.OutputMean, "load", ec.LoadDesc())
 	}
-	if availReqNum > 0 {
-		metrics.NumRequests = availReqNum
-		metrics.AvgTTFTMs = sumTTFT / float64(availReqNum)
-		metrics.AvgRequestLatencyMs = sumRequestLatency / float64(availReqNum)
-		metrics.RequestThroughput = float64(availReqNum) / metrics.TotalTimeSec
+	if measuredReqNum > 0 {
+		metrics.NumRequests = measuredReqNum
+		metrics.AvgTTFTMs = sumTTFT / float64(measuredReqNum)
+		metrics.AvgRequestLatencyMs = sumRequestLatency / float64(measuredReqNum)
+		metrics.RequestThroughput = float64(measuredReqNum) / metrics.TotalTimeSec
+		metrics.TotalInputTokens = totalInputTokens
 		metrics.TotalOutputTokens = totalOutputTokens
+		metrics.TotalTokens = totalInputTokens + totalOutputTokens
+		metrics.AvgInputTokens = float64(totalInputTokens) / float64(measuredReqNum)
+		metrics.AvgOutputTokens = float64(totalOutputTokens) / float64(measuredReqNum)
 		metrics.OutputTokenThroughput = float64(totalOutputTokens) / metrics.TotalTimeSec
+		metrics.TTFTStatsMs = computeDistStats(ttfts)
+		metrics.RequestLatencyStatsMs = computeDistStats(requestLatencies)
 	}
 	if numITLIntervals > 0 {
 		metrics.AvgITLMs = sumITL / float64(numITLIntervals)
+		metrics.ITLStatsMs = computeDistStats(itls)
+	}
+	if metrics.AchievedRequestRate < 0.9*metrics.OfferedRequestRate {
+		logger.Warn("Achieved request rate is below the offered one, the client could not keep up",
+			"offered", metrics.OfferedRequestRate, "achieved", metrics.AchievedRequestRate,
+			"model", metrics.Model, "input", ec.InputMean, "output", ec.OutputMean)
+	}
+	if !ec.Stream {
+		// Without streaming there is no first token nor token intervals to time
+		metrics.AvgTTFTMs, metrics.AvgITLMs = math.NaN(), math.NaN()
+		metrics.TTFTStatsMs, metrics.ITLStatsMs = nanDistStats(), nanDistStats()
 	}
 
 	return metrics
 }
 
+// responded tells whether the response of the request is available
+func responded(req Request, stream bool) bool {
+	return len(req.ResponseTimestamps) > 0 && (!stream || len(req.ResponseTimestamps) > 2)
+}
+
+// streamed tells whether the responses of the experiment were streamed from the stream field
+// of its payload, def is returned when the payload has none
+func streamed(exp Experiment, def bool) bool {
+	if len(exp.Requests) == 0 {
+		return def
+	}
+	var payload struct {
+		Stream *bool `json:"stream"`
+	}
+	if err := json.Unmarshal([]byte(exp.Requests[0].RequestInputs.Payload), &payload); err != nil || payload.Stream == nil {
+		return def
+	}
+	return *payload.Stream
+}
+
 func GenJSONPaths(config config.Config) ([]string, error) {
 	// Use config.GenAIPerf and concate config.ReportConf.GenAIArtfPath
 	// example: $(model)-$(inputMean)-$(outputMean)-concurrency$(concurrency)/$(RunCount)_$(concurrency)_profile.json
+	// or $(model)-$(inputMean)-$(outputMean)-request_rate$(rate)/$(RunCount)_$(rate)_profile.json
 
 	paths := []string{}
 	gp := config.GenAIPerf
@@ -177,8 +280,22 @@ func GenJSONPaths(config config.Config) ([]string, error) {
 	if len(gp.Models) == 0 {
 		return nil, fmt.Errorf("no models specified in GenAIPerf")
 	}
-	if len(gp.Concurrency) == 0 {
-		return nil, fmt.Errorf("no concurrency values specified in GenAIPerf")
+	if len(gp.Concurrency) == 0 && len(gp.RequestRates) == 0 {
+		return nil, fmt.Errorf("no concurrency nor request rate values specified in GenAIPerf")
+	}
+	for _, rate := range gp.RequestRates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid request rate %v in GenAIPerf: must be positive", rate)
+		}
+	}
+
+	// The concurrency sweep then the request rate one
+	var loads []GenAIPerfExpConf
+	for _, concurrency := range gp.Concurrency {
+		loads = append(loads, GenAIPerfExpConf{Concurrency: concurrency})
+	}
+	for _, rate := range gp.RequestRates {
+		loads = append(loads, GenAIPerfExpConf{RequestRate: rate})
 	}
 	if len(gp.Requests.RunCount) == 0 {
 		return nil, fmt.Errorf("no run counts specified in GenAIPerf")
@@ -188,13 +305,19 @@ func GenJSONPaths(config config.Config) ([]string, error) {
 	}
 
 	for _, model := range gp.Models {
+		mi, err := ParseModel(model)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model in GenAIPerf: %v", err)
+		}
+		var ec GenAIPerfExpConf
+		ec.setModel(mi)
 		for _, i := range tci {
 			for _, o := range tco {
-				for _, concurrency := range gp.Concurrency {
+				for _, load := range loads {
 					for _, runCount := range gp.Requests.RunCount {
-						path := fmt.Sprintf("%s/%s-%d-%d-concurrency%d/%d_%d_profile.json",
-							config.ReportConf.ArtfDir, model, i.Mean, o.Mean, concurrency, runCount, concurrency)
-						paths = append(paths, path)
+						ec.InputMean, ec.OutputMean = i.Mean, o.Mean
+						ec.Concurrency, ec.RequestRate, ec.RunCount = load.Concurrency, load.RequestRate, runCount
+						paths = append(paths, ec.ProfilePath(config.ReportConf.ArtfDir))
 					}
 				}
 			}
@@ -203,27 +326,24 @@ func GenJSONPaths(config config.Config) ([]string, error) {
 	return paths, nil
 }
 
+// GetConfFromPath parses the experiment config from the path of its profile export or of a checkpoint of it
 func GetConfFromPath(path string) (GenAIPerfExpConf, error) {
-	var ec GenAIPerfExpConf
 	// Example path: $(model)-$(inputMean)-$(outputMean)-concurrency$(concurrency)/$(RunCount)_$(concurrency)_profile.json
-	// Split by '/' and then by '-'
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		return ec, fmt.Errorf("invalid path format: %s", path)
+	dir, name := filepath.Base(filepath.Dir(path)), filepath.Base(profileOf(path))
+	ec, err := ParseExpDir(dir)
+	if err != nil {
+		return ec, fmt.Errorf("parsing path %s: %v", path, err)
 	}
 
-	dirName := strings.Split(parts[len(parts)-2], "-")
-	if len(dirName) < 4 {
-		return ec, fmt.Errorf("invalid directory name in path: %s", parts[len(parts)-2])
+	runCount, load, err := parseProfileName(name)
+	if err != nil {
+		return ec, fmt.Errorf("parsing path %s: %v", path, err)
 	}
-
-	modelParts := strings.Split(dirName[0], ":")
-	ec.Model = modelParts[0]
-	ec.PMSize, _ = strconv.Atoi(strings.TrimSuffix(modelParts[1], "b"))
-	ec.InputMean, _ = strconv.Atoi(dirName[1])
-	ec.OutputMean, _ = strconv.Atoi(dirName[2])
-	ec.Concurrency, _ = strconv.Atoi(strings.TrimPrefix(dirName[3], "concurrency"))
-	ec.RunCount, _ = strconv.Atoi(strings.Split(parts[len(parts)-1], "_")[0])
+	if load != ec.Load() {
+		return ec, fmt.Errorf("parsing path %s: %s %v of the profile name differs from %v of the directory",
+			path, ec.LoadMode(), load, ec.Load())
+	}
+	ec.RunCount = runCount
 
 	return ec, nil
 }
@@ -0,0 +1,294 @@
+package input
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// Experiment identity in the artifacts directory:
+// $(model)-$(inputMean)-$(outputMean)-concurrency$(concurrency)/$(runCount)_$(concurrency)_profile.json
+// or, for request rate experiments, $(model)-$(inputMean)-$(outputMean)-request_rate$(rate)/$(runCount)_$(rate)_profile.json.
+// The model is name[:tag] and may contain dashes itself, so the directory name is parsed from the right.
+// Path separators in the model (e.g. meta-llama/Llama-3.1-8B-Instruct) are percent-encoded as %2F and %5C,
+// and so is '%' itself as %25 to keep the encoding reversible.
+
+var (
+	// Parameter size such as 7b, 270m or 0.5B. The size of a mixture of experts such as 8x7b is not
+	// the number of experts times the expert size (Mixtral 8x7B has 46.7B parameters), so it is left unknown.
+	sizeRe = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)([mbt])$`)
+	// Quantization such as q4_K_M, q8_0, fp16 or awq
+	quantRe = regexp.MustCompile(`(?i)^(?:q\d+(?:_[a-z0-9]+)*|iq\d+(?:_[a-z0-9]+)*|f16|fp16|bf16|f32|fp32|fp8|int4|int8|awq|gptq)$`)
+
+	// Encoding of a model name into a directory name, and back
+	modelDirEncoder = strings.NewReplacer("%", "%25", "/", "%2F", `\`, "%5C")
+	modelDirDecoder = strings.NewReplacer("%25", "%", "%2F", "/", "%5C", `\`)
+)
+
+// ModelInfo is what a model name tells about the model
+type ModelInfo struct {
+	Name         string  // Model name without tag (e.g. qwen2.5-coder)
+	Tag          string  // Tag after ':' (e.g. 7b-instruct-q4_K_M), empty if none
+	Size         float64 // Parameter size in billions, 0 if unknown
+	Quantization string  // Quantization (e.g. q4_K_M), empty if unknown
+}
+
+// ParseModel parses a model name such as llama3.2:1b, qwen2.5-coder:7b-instruct-q4_K_M,
+// gemma3:270m, mistral, Meta-Llama-3-8B-Instruct or meta-llama/Llama-3.1-8B-Instruct.
+// The size and quantization are looked up in the tag first and then in the name.
+func ParseModel(s string) (ModelInfo, error) {
+	var mi ModelInfo
+	if s == "" {
+		return mi, fmt.Errorf("empty model name")
+	}
+	mi.Name, mi.Tag, _ = strings.Cut(s, ":")
+	if mi.Name == "" {
+		return mi, fmt.Errorf("empty name in model %q", s)
+	}
+
+	for _, segments := range [][]string{modelSegments(mi.Tag), modelSegments(mi.Name)} {
+		for _, seg := range segments {
+			if mi.Size == 0 {
+				if size, ok := parseSize(seg); ok {
+					mi.Size = size
+					continue
+				}
+			}
+			if mi.Quantization == "" && quantRe.MatchString(seg) {
+				mi.Quantization = seg
+			}
+		}
+	}
+	return mi, nil
+}
+
+// modelSegments splits a model name or tag into its dash separated segments
+func modelSegments(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "-")
+}
+
+// parseSize parses a parameter size segment into billions
+func parseSize(seg string) (float64, bool) {
+	m := sizeRe.FindStringSubmatch(seg)
+	if m == nil {
+		return 0, false
+	}
+	size, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "m":
+		size /= 1e3
+	case "t":
+		size *= 1e3
+	}
+	return size, true
+}
+
+// ServedModel returns the model name as served, i.e. name[:tag]
+func (ec GenAIPerfExpConf) ServedModel() string {
+	if ec.Tag == "" {
+		return ec.Model
+	}
+	return ec.Model + ":" + ec.Tag
+}
+
+// Streaming modes of the experiments
+const (
+	ModeStreaming    = "streaming"
+	ModeNonStreaming = "non-streaming"
+)
+
+// Mode returns whether the responses of the experiment were streamed, as ModeStreaming or ModeNonStreaming
+func (ec GenAIPerfExpConf) Mode() string {
+	if ec.Stream {
+		return ModeStreaming
+	}
+	return ModeNonStreaming
+}
+
+// Load modes of the experiments, as named in the profile exports
+const (
+	LoadConcurrency = "concurrency"  // Closed loop, a fixed number of requests in flight
+	LoadRequestRate = "request_rate" // Open loop, requests sent at a fixed rate
+)
+
+// LoadMode returns how the load of the experiment was generated, LoadConcurrency or LoadRequestRate
+func (ec GenAIPerfExpConf) LoadMode() string {
+	if ec.RequestRate > 0 {
+		return LoadRequestRate
+	}
+	return LoadConcurrency
+}
+
+// Load returns the concurrency or the request rate of the experiment, according to its load mode
+func (ec GenAIPerfExpConf) Load() float64 {
+	if ec.LoadMode() == LoadRequestRate {
+		return ec.RequestRate
+	}
+	return float64(ec.Concurrency)
+}
+
+// LoadDesc describes the load of the experiment, e.g. "concurrency 4" or "request rate 2.5/s"
+func (ec GenAIPerfExpConf) LoadDesc() string {
+	if ec.LoadMode() == LoadRequestRate {
+		return fmt.Sprintf("request rate %s/s", formatRate(ec.RequestRate))
+	}
+	return fmt.Sprintf("concurrency %d", ec.Concurrency)
+}
+
+// formatRate formats a request rate with as few digits as needed, e.g. 2 or 0.5
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+// loadName returns the load of the experiment as written in its profile name
+func (ec GenAIPerfExpConf) loadName() string {
+	if ec.LoadMode() == LoadRequestRate {
+		return formatRate(ec.RequestRate)
+	}
+	return strconv.Itoa(ec.Concurrency)
+}
+
+// ExpDir returns the directory name of the experiment
+func (ec GenAIPerfExpConf) ExpDir() string {
+	return fmt.Sprintf("%s-%d-%d-%s%s", modelDirEncoder.Replace(ec.ServedModel()), ec.InputMean, ec.OutputMean, ec.LoadMode(), ec.loadName())
+}
+
+// ProfileName returns the file name of the profile export of the experiment
+func (ec GenAIPerfExpConf) ProfileName() string {
+	return fmt.Sprintf("%d_%s%s", ec.RunCount, ec.loadName(), profileSuffix)
+}
+
+// ProfilePath returns the path of the profile export of the experiment under artfDir
+func (ec GenAIPerfExpConf) ProfilePath(artfDir string) string {
+	return filepath.Join(artfDir, ec.ExpDir(), ec.ProfileName())
+}
+
+// ParseExpDir parses an experiment directory name such as qwen2.5-coder:7b-150-50-concurrency4,
+// llama3.2:1b-150-50-request_rate2.5 or meta-llama%2FLlama-3.1-8B-Instruct-150-50-concurrency4.
+// The run count is left as 0.
+func ParseExpDir(dir string) (GenAIPerfExpConf, error) {
+	var ec GenAIPerfExpConf
+	parts := strings.Split(dir, "-")
+	n := len(parts)
+	if n < 4 {
+		return ec, fmt.Errorf("invalid experiment directory %q: expected <model>-<input>-<output>-concurrency<N> or request_rate<R>", dir)
+	}
+
+	var err error
+	if concurrency, ok := strings.CutPrefix(parts[n-1], LoadConcurrency); ok {
+		if ec.Concurrency, err = strconv.Atoi(concurrency); err != nil {
+			return ec, fmt.Errorf("invalid concurrency in experiment directory %q: %v", dir, err)
+		}
+	} else if rate, ok := strings.CutPrefix(parts[n-1], LoadRequestRate); ok {
+		if ec.RequestRate, err = parseRate(rate); err != nil {
+			return ec, fmt.Errorf("invalid request rate in experiment directory %q: %v", dir, err)
+		}
+	} else {
+		return ec, fmt.Errorf("invalid experiment directory %q: missing concurrency<N> or request_rate<R> suffix", dir)
+	}
+	if ec.OutputMean, err = strconv.Atoi(parts[n-2]); err != nil {
+		return ec, fmt.Errorf("invalid output mean in experiment directory %q: %v", dir, err)
+	}
+	if ec.InputMean, err = strconv.Atoi(parts[n-3]); err != nil {
+		return ec, fmt.Errorf("invalid input mean in experiment directory %q: %v", dir, err)
+	}
+
+	mi, err := ParseModel(modelDirDecoder.Replace(strings.Join(parts[:n-3], "-")))
+	if err != nil {
+		return ec, fmt.Errorf("invalid model in experiment directory %q: %v", dir, err)
+	}
+	ec.setModel(mi)
+	return ec, nil
+}
+
+// setModel sets the model fields of the experiment config
+func (ec *GenAIPerfExpConf) setModel(mi ModelInfo) {
+	ec.Model, ec.Tag = mi.Name, mi.Tag
+	ec.PMSize = mi.Size
+	ec.Quantization = mi.Quantization
+}
+
+// applyModelConf overrides the model metadata parsed from the model name with the one of the report config
+func (ec *GenAIPerfExpConf) applyModelConf(rc config.ReportConf) error {
+	mc, ok := rc.ModelConfFor(ec.ServedModel())
+	if !ok {
+		return nil
+	}
+	if mc.Params != "" {
+		size, ok := parseSize(mc.Params)
+		if !ok {
+			return fmt.Errorf("invalid params %q of model %s: expected a number with unit m, b or t", mc.Params, ec.ServedModel())
+		}
+		ec.PMSize = size
+	}
+	if mc.Quantization != "" {
+		ec.Quantization = mc.Quantization
+	}
+	if mc.Family != "" {
+		ec.Family = mc.Family
+	}
+	return nil
+}
+
+// FormatParamSize formats a parameter size in billions with its unit, e.g. 270m, 1.5b or 1t
+func FormatParamSize(size float64) string {
+	format := func(v float64, unit string) string {
+		// Round away the floating point error of the unit conversion
+		return strconv.FormatFloat(math.Round(v*1e3)/1e3, 'f', -1, 64) + unit
+	}
+	switch {
+	case size <= 0:
+		return "unknown"
+	case size < 1:
+		return format(size*1e3, "m")
+	case size >= 1e3:
+		return format(size/1e3, "t")
+	default:
+		return format(size, "b")
+	}
+}
+
+// parseRate parses a positive request rate (unit: requests per second)
+func parseRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return 0, fmt.Errorf("request rate %s is not positive", s)
+	}
+	return rate, nil
+}
+
+// parseProfileName parses a profile export file name such as 10_4_profile.json into its run count
+// and its load, the concurrency or the request rate of the experiment
+func parseProfileName(name string) (int, float64, error) {
+	base, ok := strings.CutSuffix(name, profileSuffix)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid profile name %q: missing %s suffix", name, profileSuffix)
+	}
+	runCount, load, found := strings.Cut(base, "_")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid profile name %q: expected <runCount>_<load>%s", name, profileSuffix)
+	}
+	rc, err := strconv.Atoi(runCount)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid run count in profile name %q: %v", name, err)
+	}
+	l, err := strconv.ParseFloat(load, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid load in profile name %q: %v", name, err)
+	}
+	return rc, l, nil
+}
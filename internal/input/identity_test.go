@@ -0,0 +1,186 @@
+package input
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  ModelInfo
+	}{
+		{"llama3.2:1b", ModelInfo{Name: "llama3.2", Tag: "1b", Size: 1}},
+		{"qwen2.5-coder:7b-instruct-q4_K_M", ModelInfo{Name: "qwen2.5-coder", Tag: "7b-instruct-q4_K_M", Size: 7, Quantization: "q4_K_M"}},
+		{"gemma3:270m", ModelInfo{Name: "gemma3", Tag: "270m", Size: 0.27}},
+		{"Meta-Llama-3-8B-Instruct", ModelInfo{Name: "Meta-Llama-3-8B-Instruct", Size: 8}},
+		{"mistral", ModelInfo{Name: "mistral"}},
+		{"llama3.1:8b-instruct-fp16", ModelInfo{Name: "llama3.1", Tag: "8b-instruct-fp16", Size: 8, Quantization: "fp16"}},
+		{"Qwen2.5-0.5B-Instruct-AWQ", ModelInfo{Name: "Qwen2.5-0.5B-Instruct-AWQ", Size: 0.5, Quantization: "AWQ"}},
+		{"mistral:latest", ModelInfo{Name: "mistral", Tag: "latest"}},
+		// Mixtures of experts have no size unless configured
+		{"mixtral:8x7b", ModelInfo{Name: "mixtral", Tag: "8x7b"}},
+		{"mixtral:8x7b-instruct-v0.1-q4_0", ModelInfo{Name: "mixtral", Tag: "8x7b-instruct-v0.1-q4_0", Quantization: "q4_0"}},
+		{"Mixtral-8x7B-Instruct-v0.1", ModelInfo{Name: "Mixtral-8x7B-Instruct-v0.1"}},
+		// Hugging Face ids as served by vLLM
+		{"meta-llama/Llama-3.1-8B-Instruct", ModelInfo{Name: "meta-llama/Llama-3.1-8B-Instruct", Size: 8}},
+		{"Qwen/Qwen2.5-0.5B-Instruct-AWQ", ModelInfo{Name: "Qwen/Qwen2.5-0.5B-Instruct-AWQ", Size: 0.5, Quantization: "AWQ"}},
+		{`models\llama`, ModelInfo{Name: `models\llama`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, err := ParseModel(tt.model)
+			if err != nil {
+				t.Fatalf("ParseModel(%q) failed: %v", tt.model, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseModel(%q) = %+v, want %+v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModelInvalid(t *testing.T) {
+	for _, model := range []string{"", ":7b"} {
+		t.Run(model, func(t *testing.T) {
+			if mi, err := ParseModel(model); err == nil {
+				t.Errorf("ParseModel(%q) = %+v, want an error", model, mi)
+			}
+		})
+	}
+}
+
+func TestParseExpDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want GenAIPerfExpConf
+	}{
+		{
+			"llama3.2:1b-150-50-concurrency4",
+			GenAIPerfExpConf{Model: "llama3.2", Tag: "1b", PMSize: 1, InputMean: 150, OutputMean: 50, Concurrency: 4},
+		},
+		{
+			"qwen2.5-coder:7b-instruct-q4_K_M-650-200-concurrency1",
+			GenAIPerfExpConf{Model: "qwen2.5-coder", Tag: "7b-instruct-q4_K_M", PMSize: 7, Quantization: "q4_K_M",
+				InputMean: 650, OutputMean: 200, Concurrency: 1},
+		},
+		{
+			"Meta-Llama-3-8B-Instruct-150-50-concurrency16",
+			GenAIPerfExpConf{Model: "Meta-Llama-3-8B-Instruct", PMSize: 8, InputMean: 150, OutputMean: 50, Concurrency: 16},
+		},
+		{
+			"mistral-150-50-concurrency2",
+			GenAIPerfExpConf{Model: "mistral", InputMean: 150, OutputMean: 50, Concurrency: 2},
+		},
+		{
+			"gemma3:270m-150-50-request_rate2.5",
+			GenAIPerfExpConf{Model: "gemma3", Tag: "270m", PMSize: 0.27, InputMean: 150, OutputMean: 50, RequestRate: 2.5},
+		},
+		{
+			"llama3.2:1b-150-50-request_rate4",
+			GenAIPerfExpConf{Model: "llama3.2", Tag: "1b", PMSize: 1, InputMean: 150, OutputMean: 50, RequestRate: 4},
+		},
+		// Path separators and '%' of the model are percent-encoded
+		{
+			"meta-llama%2FLlama-3.1-8B-Instruct-150-50-concurrency4",
+			GenAIPerfExpConf{Model: "meta-llama/Llama-3.1-8B-Instruct", PMSize: 8, InputMean: 150, OutputMean: 50, Concurrency: 4},
+		},
+		{
+			"org%2Fsub%2Fmodel-7b-650-200-request_rate0.5",
+			GenAIPerfExpConf{Model: "org/sub/model-7b", PMSize: 7, InputMean: 650, OutputMean: 200, RequestRate: 0.5},
+		},
+		{
+			"models%5Cllama-150-50-concurrency1",
+			GenAIPerfExpConf{Model: `models\llama`, InputMean: 150, OutputMean: 50, Concurrency: 1},
+		},
+		{
+			"int8%25%2Fmodel-150-50-concurrency1",
+			GenAIPerfExpConf{Model: "int8%/model", InputMean: 150, OutputMean: 50, Concurrency: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, err := ParseExpDir(tt.dir)
+			if err != nil {
+				t.Fatalf("ParseExpDir(%q) failed: %v", tt.dir, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseExpDir(%q) = %+v, want %+v", tt.dir, got, tt.want)
+			}
+			// The directory name is generated back from the config
+			if dir := got.ExpDir(); dir != tt.dir {
+				t.Errorf("ExpDir() = %q, want %q", dir, tt.dir)
+			}
+		})
+	}
+}
+
+func TestParseExpDirInvalid(t *testing.T) {
+	for _, dir := range []string{
+		"",
+		"llama3.2:1b-150-concurrency4",
+		"llama3.2:1b-150-50-4",
+		"llama3.2:1b-150-50-concurrency",
+		"llama3.2:1b-150-50-concurrencyX",
+		"llama3.2:1b-150-50-request_rate0",
+		"llama3.2:1b-150-50-request_rate-1",
+		"llama3.2:1b-150-short-concurrency4",
+		"llama3.2:1b-long-50-concurrency4",
+		"-150-50-concurrency4",
+		":1b-150-50-concurrency4",
+	} {
+		t.Run(dir, func(t *testing.T) {
+			if ec, err := ParseExpDir(dir); err == nil {
+				t.Errorf("ParseExpDir(%q) = %+v, want an error", dir, ec)
+			}
+		})
+	}
+}
+
+func TestGetConfFromPath(t *testing.T) {
+	ec, err := GetConfFromPath("/artifacts/gemma3:270m-150-50-concurrency4/10_4_profile.json")
+	if err != nil {
+		t.Fatalf("GetConfFromPath failed: %v", err)
+	}
+	if ec.RunCount != 10 || ec.Concurrency != 4 {
+		t.Errorf("GetConfFromPath = %+v, want run count 10 and concurrency 4", ec)
+	}
+
+	ec, err = GetConfFromPath("/artifacts/meta-llama%2FLlama-3.1-8B-Instruct-150-50-concurrency4/10_4_profile.json")
+	if err != nil {
+		t.Fatalf("GetConfFromPath failed: %v", err)
+	}
+	if ec.ServedModel() != "meta-llama/Llama-3.1-8B-Instruct" || ec.RunCount != 10 {
+		t.Errorf("GetConfFromPath = %+v, want model meta-llama/Llama-3.1-8B-Instruct and run count 10", ec)
+	}
+
+	// The load of the profile name must match the directory
+	if _, err := GetConfFromPath("/artifacts/gemma3:270m-150-50-concurrency4/10_2_profile.json"); err == nil {
+		t.Error("GetConfFromPath with mismatched concurrency succeeded, want an error")
+	}
+}
+
+func TestGenJSONPathsRoundTrip(t *testing.T) {
+	c := checkpointConf("/artifacts")
+	c.GenAIPerf.Models = []string{"meta-llama/Llama-3.1-8B-Instruct", "llama3.2:1b"}
+	paths, err := GenJSONPaths(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 4 {
+		t.Fatalf("GenJSONPaths = %q, want 4 paths", paths)
+	}
+	for i, path := range paths {
+		// The model is a single directory
+		if filepath.Dir(filepath.Dir(path)) != "/artifacts" {
+			t.Errorf("GenJSONPaths path %q is not an experiment directory of /artifacts", path)
+		}
+		ec, err := GetConfFromPath(path)
+		if err != nil {
+			t.Fatalf("GetConfFromPath(%q) failed: %v", path, err)
+		}
+		if want := c.GenAIPerf.Models[i/2]; ec.ServedModel() != want {
+			t.Errorf("GetConfFromPath(%q) model = %q, want %q", path, ec.ServedModel(), want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// nanPowerMetrics returns power metrics with every component missing
+func nanPowerMetrics() KeplerPowerMetrics {
+	var pm KeplerPowerMetrics
+	v := reflect.ValueOf(&pm).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		v.Field(i).SetFloat(math.NaN())
+	}
+	return pm
+}
+
+// combinePower applies f to each component of a and b
+func combinePower(a, b KeplerPowerMetrics, f func(a, b float64) float64) KeplerPowerMetrics {
+	var pm KeplerPowerMetrics
+	va, vb, v := reflect.ValueOf(a), reflect.ValueOf(b), reflect.ValueOf(&pm).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		v.Field(i).SetFloat(f(va.Field(i).Float(), vb.Field(i).Float()))
+	}
+	return pm
+}
+
+// scalePower multiplies each component of pm by k
+func scalePower(pm KeplerPowerMetrics, k float64) KeplerPowerMetrics {
+	return combinePower(pm, pm, func(a, _ float64) float64 { return a * k })
+}
+
+// idleWindows returns the quiet windows around the sweep spanning from begin to end
+func idleWindows(ib config.IdleBaselineConf, begin, end time.Time) ([][2]time.Time, error) {
+	before := [2]time.Time{begin.Add(-ib.Margin - ib.Duration), begin.Add(-ib.Margin)}
+	after := [2]time.Time{end.Add(ib.Margin), end.Add(ib.Margin + ib.Duration)}
+	switch ib.Position {
+	case "", config.IdleBefore:
+		return [][2]time.Time{before}, nil
+	case config.IdleAfter:
+		return [][2]time.Time{after}, nil
+	case config.IdleBoth:
+		return [][2]time.Time{before, after}, nil
+	default:
+		return nil, fmt.Errorf("unknown idle baseline position: %s", ib.Position)
+	}
+}
+
+// idlePower measures the average power (unit: watts) of each component of the node and
+// the pod of model over the idle windows
+func idlePower(ctx context.Context, model string, windows [][2]time.Time, source EnergySource) (KeplerPowerMetrics, error) {
+	var energy KeplerPowerMetrics
+	var seconds float64
+	for _, w := range windows {
+		pm, err := source.Energy(ctx, model, w[0], w[1])
+		if err != nil {
+			return KeplerPowerMetrics{}, err
+		}
+		energy = combinePower(energy, pm, func(a, b float64) float64 { return a + b })
+		seconds += w[1].Sub(w[0]).Seconds()
+	}
+	return scalePower(energy, 1/seconds), nil
+}
+
+// sweepSpan returns the time spanned by every repetition of every experiment
+func (emp ExpMetricPair) sweepSpan() (begin, end time.Time) {
+	for _, em := range emp {
+		reps := em.Reps
+		if len(reps) == 0 {
+			reps = []ExpMetrics{em}
+		}
+		for _, rep := range reps {
+			if begin.IsZero() || rep.Begin.Before(begin) {
+				begin = rep.Begin
+			}
+			if rep.End.After(end) {
+				end = rep.End
+			}
+		}
+	}
+	return begin, end
+}
+
+// IdleBaselineEnd returns the end of the last idle window of the sweep, the zero time without idle baseline
+func IdleBaselineEnd(c config.Config, emp ExpMetricPair) time.Time {
+	ib := c.ReportConf.IdleBaseline
+	if ib.Duration <= 0 || len(emp) == 0 {
+		return time.Time{}
+	}
+	begin, end := emp.sweepSpan()
+	windows, err := idleWindows(ib, begin, end)
+	if err != nil {
+		return time.Time{}
+	}
+	return windows[len(windows)-1][1]
+}
+
+// applyIdleBaseline measures the idle power around the sweep and sets the idle and net energy of every experiment.
+// The idle windows span the whole sweep, including its repetitions. While the window after the sweep
+// is not complete, i.e. experiments are still running or it is not over, idle and net energy are left missing.
+func applyIdleBaseline(ctx context.Context, c config.Config, emp ExpMetricPair, source EnergySource, logger *slog.Logger) error {
+	ib := c.ReportConf.IdleBaseline
+	if ib.Duration <= 0 || len(emp) == 0 {
+		return nil
+	}
+
+	begin, end := emp.sweepSpan()
+	windows, err := idleWindows(ib, begin, end)
+	if err != nil {
+		return err
+	}
+	if ib.Position == config.IdleAfter || ib.Position == config.IdleBoth {
+		// The sweep is only followed by an idle window once its last experiment has completed
+		var pending int
+		if c.GenAIPerf.Enabled.Checkpoint {
+			if pending, err = PendingExperiments(c, emp); err != nil {
+				logger.Warn("Cannot tell the pending experiments, net energy is missing", "error", err)
+				return nil
+			}
+		}
+		if last := windows[len(windows)-1][1]; pending > 0 || last.After(time.Now()) {
+			logger.Warn("Idle window after the sweep is not complete yet, net energy is missing",
+				"pending", pending, "window_end", last)
+			return nil
+		}
+	}
+
+	// The pod selector depends on the served model, measure every model before changing the metrics
+	powers := make(map[string]KeplerPowerMetrics)
+	for ec := range emp {
+		model := ec.ServedModel()
+		if _, ok := powers[model]; ok {
+			continue
+		}
+		w, err := idlePower(ctx, model, windows, source)
+		if err != nil && c.ReportConf.Watch > 0 && ctx.Err() == nil {
+			// The energy source may not have recorded the idle window yet, the next report will measure it
+			logger.Warn("Cannot measure the idle power yet, net energy is missing", "model", model, "error", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("measuring idle power of %s: %v", model, err)
+		}
+		powers[model] = w
+		logger.Info("Idle baseline measured", "model", model, "windows", len(windows),
+			"node_platform_w", w.NodePlatformJ, "node_gpu_w", w.NodeGPUJ, "node_package_w", w.NodePackageJ)
+	}
+
+	emp.updateReps(func(ec GenAIPerfExpConf, m *ExpMetrics) {
+		m.IdleM = scalePower(powers[ec.ServedModel()], m.End.Sub(m.Begin).Seconds())
+		m.NetM = combinePower(m.PowerM, m.IdleM, func(a, b float64) float64 { return a - b })
+	})
+	return nil
+}
@@ -0,0 +1,60 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+func TestApplyIdleBaselineIncompleteSweep(t *testing.T) {
+	dir := copyFixture(t, func(name string) string { return name })
+
+	tests := []struct {
+		name     string
+		position string
+		complete bool // The running experiment has completed
+		wantNet  bool
+	}{
+		{name: "before", position: config.IdleBefore, wantNet: true},
+		{name: "after a running sweep", position: config.IdleAfter},
+		{name: "both around a running sweep", position: config.IdleBoth},
+		{name: "after a complete sweep", position: config.IdleAfter, complete: true, wantNet: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.complete {
+				exp := filepath.Join(dir, "mistral-150-50-concurrency4")
+				if err := os.Rename(filepath.Join(exp, "4_4_profile.checkpoint-2.json"), filepath.Join(exp, "4_4_profile.json")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := checkpointConf(dir)
+			c.ReportConf.IdleBaseline = config.IdleBaselineConf{Duration: 2 * time.Second, Margin: time.Second, Position: tt.position}
+
+			var logs bytes.Buffer
+			emp, err := GenExpMetricPair(context.Background(), c, slog.New(slog.NewTextHandler(&logs, nil)))
+			if err != nil {
+				t.Fatalf("GenExpMetricPair failed: %v", err)
+			}
+			for ec, em := range emp {
+				if hasNet := !math.IsNaN(em.NetM.NodePlatformJ); hasNet != tt.wantNet {
+					t.Errorf("concurrency %d: net energy %v, want it measured %v", ec.Concurrency, em.NetM.NodePlatformJ, tt.wantNet)
+				}
+				if math.IsNaN(em.PowerM.NodePlatformJ) {
+					t.Errorf("concurrency %d: gross energy missing", ec.Concurrency)
+				}
+			}
+			if warned := strings.Contains(logs.String(), "Idle window after the sweep is not complete yet"); warned == tt.wantNet {
+				t.Errorf("incomplete idle window warned %v, want %v:\n%s", warned, !tt.wantNet, logs.String())
+			}
+		})
+	}
+}
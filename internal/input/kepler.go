@@ -1,48 +1,228 @@
 package input
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/explorerray/itpe-report/config"
 	"github.com/explorerray/itpe-report/internal/client/promclient"
 )
 
+// KeplerPowerMetrics holds the energy (unit: joules) of each component reported by Kepler.
+// Other energy sources map their readings onto the same components.
 type KeplerPowerMetrics struct {
-	NodePlatformJ float64
-	NodeGPUJ      float64
-	NodePackageJ  float64
-	NodeDRAMJ     float64
-	NodeOtherJ    float64
-	PodGPUJ       float64
-	PodDRAMJ      float64
-	PodPackageJ   float64
-	PodPlatformJ  float64
-	PodOtherJ     float64
-}
-
-func GetPowerMetrics(exp Experiment) KeplerPowerMetrics {
-	reqs := exp.Requests
-	lastReq := reqs[len(reqs)-1]
-	// for Power metrics collected by Kepler
-	expBegin := reqs[0].Timestamp
-	expEnd := lastReq.ResponseTimestamps[len(lastReq.ResponseTimestamps)-1]
-
-	expBeginQuery := promclient.MakeKeplerQueryInfo(time.Unix(0, expBegin), "ollama")
-	expEndQuery := promclient.MakeKeplerQueryInfo(time.Unix(0, expEnd), "ollama")
-
-	beginResp := promclient.MultiQuery(expBeginQuery)
-	endResp := promclient.MultiQuery(expEndQuery)
-
-	// Sum up if there are several results
-	return KeplerPowerMetrics{
-		NodePlatformJ: promclient.SumResults(endResp[0].Results) - promclient.SumResults(beginResp[0].Results),
-		NodeGPUJ:      promclient.SumResults(endResp[1].Results) - promclient.SumResults(beginResp[1].Results),
-		NodePackageJ:  promclient.SumResults(endResp[2].Results) - promclient.SumResults(beginResp[2].Results),
-		NodeDRAMJ:     promclient.SumResults(endResp[3].Results) - promclient.SumResults(beginResp[3].Results),
-		NodeOtherJ:    promclient.SumResults(endResp[4].Results) - promclient.SumResults(beginResp[4].Results),
-		PodGPUJ:       promclient.SumResults(endResp[5].Results) - promclient.SumResults(beginResp[5].Results),
-		PodDRAMJ:      promclient.SumResults(endResp[6].Results) - promclient.SumResults(beginResp[6].Results),
-		PodPackageJ:   promclient.SumResults(endResp[7].Results) - promclient.SumResults(beginResp[7].Results),
-		PodPlatformJ:  promclient.SumResults(endResp[8].Results) - promclient.SumResults(beginResp[8].Results),
-		PodOtherJ:     promclient.SumResults(endResp[9].Results) - promclient.SumResults(beginResp[9].Results),
+	NodePlatformJ float64 `json:"node_platform_j"`
+	NodeGPUJ      float64 `json:"node_gpu_j"`
+	NodePackageJ  float64 `json:"node_package_j"`
+	NodeDRAMJ     float64 `json:"node_dram_j"`
+	NodeOtherJ    float64 `json:"node_other_j"`
+	PodGPUJ       float64 `json:"pod_gpu_j"`
+	PodDRAMJ      float64 `json:"pod_dram_j"`
+	PodPackageJ   float64 `json:"pod_package_j"`
+	PodPlatformJ  float64 `json:"pod_platform_j"`
+	PodOtherJ     float64 `json:"pod_other_j"`
+}
+
+// setComponent sets the energy of a component named as in promclient.KeplerComponents
+func (pm *KeplerPowerMetrics) setComponent(component string, joules float64) {
+	switch component {
+	case promclient.NodePlatform:
+		pm.NodePlatformJ = joules
+	case promclient.NodeGPU:
+		pm.NodeGPUJ = joules
+	case promclient.NodePackage:
+		pm.NodePackageJ = joules
+	case promclient.NodeDRAM:
+		pm.NodeDRAMJ = joules
+	case promclient.NodeOther:
+		pm.NodeOtherJ = joules
+	case promclient.PodGPU:
+		pm.PodGPUJ = joules
+	case promclient.PodDRAM:
+		pm.PodDRAMJ = joules
+	case promclient.PodPackage:
+		pm.PodPackageJ = joules
+	case promclient.PodPlatform:
+		pm.PodPlatformJ = joules
+	case promclient.PodOther:
+		pm.PodOtherJ = joules
+	}
+}
+
+// keplerSource reads the Kepler energy counters from Prometheus
+type keplerSource struct {
+	rc     config.ReportConf
+	schema promclient.KeplerSchema
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	batches map[string][]counterBatch // Prefetched counters by model
+
+	fallbacks sync.Map // Components whose fallback was logged
+}
+
+// counterBatch holds the raw samples of the counters of a model over several experiments
+type counterBatch struct {
+	begin, end time.Time                            // Span of the experiments, padding excluded
+	responses  map[string]promclient.SeriesResponse // By query
+}
+
+func newKeplerSource(ctx context.Context, rc config.ReportConf, logger *slog.Logger) (*keplerSource, error) {
+	schema, err := promclient.DetectKeplerSchema(ctx, rc.KeplerSchema)
+	if err != nil {
+		return nil, fmt.Errorf("detecting Kepler schema: %v", err)
+	}
+	var components []string
+	for _, c := range promclient.KeplerComponents {
+		if _, ok := schema.Counters[c]; ok {
+			components = append(components, c)
+		}
+	}
+	logger.Info("Using Kepler schema", "schema", schema.Name, "components", components)
+	return &keplerSource{rc: rc, schema: schema, logger: logger, batches: make(map[string][]counterBatch)}, nil
+}
+
+// keplerSelector converts the selector of a model into matchers on the node counters and a pod selector
+func keplerSelector(ks config.KeplerSelector) ([]promclient.Matcher, promclient.PodSelector) {
+	var nodeMatchers []promclient.Matcher
+	for _, l := range ks.Labels {
+		nodeMatchers = append(nodeMatchers, promclient.Matcher{Name: l.Name, Type: l.Type, Value: l.Value})
+	}
+	pod := promclient.PodSelector{
+		ContainerName: ks.ContainerName,
+		PodName:       ks.PodName,
+		Namespace:     ks.Namespace,
+	}
+	if pod == (promclient.PodSelector{}) {
+		// Nothing configured, keep the historical default of an Ollama deployment
+		pod.ContainerName = "ollama"
+	}
+	return nodeMatchers, pod
+}
+
+// Energy integrates each counter over [begin, end] from its raw samples.
+// Counters whose query failed or matched no series are NaN.
+func (ks *keplerSource) Energy(ctx context.Context, model string, begin, end time.Time) (KeplerPowerMetrics, error) {
+	nodeMatchers, pod := keplerSelector(ks.rc.KeplerSelectorFor(model))
+	queries, err := ks.schema.Queries(nodeMatchers, pod)
+	if err != nil {
+		return nanPowerMetrics(), fmt.Errorf("building Kepler queries for %s: %v", model, err)
+	}
+
+	joules := make(map[string]float64)
+	failed := make(map[string]string) // Failure by component
+	unmatched := make(map[string]bool)
+	pad := ks.rc.QueryPadding
+	for _, q := range queries {
+		resp, ok := ks.prefetched(model, q.Query, begin, end)
+		if !ok {
+			resp = promclient.QuerySamples(ctx, q.Query, begin.Add(-pad), end.Add(pad))
+		}
+		if len(resp.Warnings) > 0 {
+			ks.logger.Warn("Prometheus returned warnings", "query", q.Query, "warnings", resp.Warnings)
+		}
+		switch {
+		case resp.Error != nil:
+			failed[q.Component] = resp.Error.Error()
+			joules[q.Component] = math.NaN()
+		case len(resp.Results) == 0:
+			failed[q.Component] = fmt.Sprintf("no series matched %s", q.Query)
+			joules[q.Component] = math.NaN()
+			unmatched[q.Component] = true
+		default:
+			// Sum up if there are several series
+			joules[q.Component] = promclient.SumIncrease(resp.Results, begin, end)
+			if math.IsNaN(joules[q.Component]) {
+				failed[q.Component] = fmt.Sprintf("too few samples of %s to compute the increase", q.Query)
+			}
+		}
+	}
+	for component, parts := range ks.schema.Fallbacks {
+		if !unmatched[component] {
+			continue
+		}
+		sum := 0.0
+		for _, part := range parts {
+			j, ok := joules[part]
+			if !ok {
+				j = math.NaN()
+			}
+			sum += j
+		}
+		if !math.IsNaN(sum) {
+			joules[component] = sum
+			delete(failed, component)
+			ks.logFallback(component, parts)
+		}
+	}
+
+	var pm KeplerPowerMetrics
+	var failures []string
+	for _, q := range queries {
+		pm.setComponent(q.Component, joules[q.Component])
+		if f, ok := failed[q.Component]; ok {
+			failures = append(failures, f)
+		}
+	}
+	if len(failures) > 0 {
+		return pm, fmt.Errorf("%d of %d Kepler queries failed: %s", len(failures), len(queries), strings.Join(failures, "; "))
+	}
+	return pm, nil
+}
+
+// logFallback logs once that the energy of component is the sum of parts
+func (ks *keplerSource) logFallback(component string, parts []string) {
+	if _, logged := ks.fallbacks.LoadOrStore(component, true); !logged {
+		ks.logger.Info("Counter of the component matches no series, using the sum of other components",
+			"schema", ks.schema.Name, "component", component, "sum_of", parts)
+	}
+}
+
+// Prefetch fetches the samples of every counter of model over [begin, end] with a single query per counter
+func (ks *keplerSource) Prefetch(ctx context.Context, model string, begin, end time.Time) error {
+	nodeMatchers, pod := keplerSelector(ks.rc.KeplerSelectorFor(model))
+	queries, err := ks.schema.Queries(nodeMatchers, pod)
+	if err != nil {
+		return fmt.Errorf("building Kepler queries for %s: %v", model, err)
+	}
+
+	batch := counterBatch{begin: begin, end: end, responses: make(map[string]promclient.SeriesResponse)}
+	pad := ks.rc.QueryPadding
+	for _, q := range queries {
+		resp := promclient.QuerySamples(ctx, q.Query, begin.Add(-pad), end.Add(pad))
+		if len(resp.Warnings) > 0 {
+			ks.logger.Warn("Prometheus returned warnings", "query", q.Query, "warnings", resp.Warnings)
+		}
+		batch.responses[q.Query] = resp
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.batches[model] = append(ks.batches[model], batch)
+	return nil
+}
+
+// prefetched returns the samples of query over the window [begin, end] padded, as QuerySamples would,
+// when a prefetched batch covers the window and its query succeeded
+func (ks *keplerSource) prefetched(model, query string, begin, end time.Time) (promclient.SeriesResponse, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, b := range ks.batches[model] {
+		if begin.Before(b.begin) || end.After(b.end) {
+			continue
+		}
+		resp, ok := b.responses[query]
+		if !ok || resp.Error != nil {
+			// Failed queries are retried for each experiment
+			return promclient.SeriesResponse{}, false
+		}
+		pad := ks.rc.QueryPadding
+		return resp.Within(begin.Add(-pad), end.Add(pad)), true
 	}
+	return promclient.SeriesResponse{}, false
 }
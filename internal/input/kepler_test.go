@@ -0,0 +1,73 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+	"github.com/explorerray/itpe-report/internal/client/promclient"
+)
+
+func TestKeplerSourcePrefetchTimeout(t *testing.T) {
+	begin := time.Unix(1_700_000_000, 0)
+	end := begin.Add(time.Hour) // A whole default batch window
+
+	// Prometheus takes 5s to return an hour of raw samples and aborts the queries exceeding their timeout
+	const evalTime = 5 * time.Second
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/label/__name__/values") {
+			fmt.Fprint(w, `{"status":"success","data":["kepler_node_platform_joules_total"]}`)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing the query: %v", err)
+		}
+		if timeout, err := time.ParseDuration(r.Form.Get("timeout")); err != nil || timeout < evalTime {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"status":"error","errorType":"timeout","error":"query timed out after %s"}`, r.Form.Get("timeout"))
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%d,"0"],[%d,"3600"]]}]}}`,
+			begin.Unix(), end.Unix())
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    bool // The batch is prefetched
+	}{
+		{name: "default", timeout: config.DefaultConfig().ReportConf.PromClient.QueryTimeout, want: true},
+		{name: "too short", timeout: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := promclient.Init(srv.URL, promclient.ClientOptions{QueryTimeout: tt.timeout}); err != nil {
+				t.Fatal(err)
+			}
+			rc := config.DefaultConfig().ReportConf
+			ks, err := newKeplerSource(context.Background(), rc, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatalf("newKeplerSource failed: %v", err)
+			}
+			if err := ks.Prefetch(context.Background(), "mistral", begin, end); err != nil {
+				t.Fatalf("Prefetch failed: %v", err)
+			}
+			queries, err := ks.schema.Queries(keplerSelector(rc.KeplerSelectorFor("mistral")))
+			if err != nil || len(queries) != 1 {
+				t.Fatalf("Queries = %v, %v, want the node platform counter", queries, err)
+			}
+			if _, got := ks.prefetched("mistral", queries[0].Query, begin, end); got != tt.want {
+				t.Errorf("prefetched with a %v timeout = %v, want %v", tt.timeout, got, tt.want)
+			}
+		})
+	}
+}
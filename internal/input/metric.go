@@ -1,19 +1,52 @@
 package input
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"math"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/explorerray/itpe-report/config"
 )
 
 type ExpMetrics struct {
-	PerfM  GenAIPerfMetrics
-	PowerM KeplerPowerMetrics
+	PerfM  GenAIPerfMetrics   `json:"perf"`
+	PowerM KeplerPowerMetrics `json:"power"` // Gross energy
+	// Idle energy of the node over the experiment duration and the energy net of it,
+	// NaN without an idle baseline
+	IdleM KeplerPowerMetrics `json:"idle"`
+	NetM  KeplerPowerMetrics `json:"net"`
+	// Carbon emissions and electricity cost of the gross energy
+	Footprint Footprint `json:"footprint"`
+	// Average, peak and p95 power drawn during the experiment, NaN when the source has no series
+	PowerW PowerSummary `json:"power_w"`
+	// Power drawn over time by each component and the requests in flight
+	PowerSeries map[string][]PowerSample `json:"-"`
+	Requests    []RequestSpan            `json:"-"`
+	// Time window of the experiment
+	Begin time.Time `json:"-"`
+	End   time.Time `json:"-"`
+	// Metrics of each repetition when the experiment was repeated, PerfM and PowerM then hold their mean
+	Reps []ExpMetrics `json:"-"`
+	// Repetitions of the sweep the experiment was expected in, 0 if unknown
+	ExpectedReps int `json:"-"`
+	// Read from a checkpoint of the experiment still running, or one of its repetitions was
+	Partial bool `json:"partial"`
 }
 
 type ExpMetricPair map[GenAIPerfExpConf]ExpMetrics
 
-func GenExpMetricPair(c config.Config, logger *slog.Logger) (ExpMetricPair, error) {
+// GenExpMetricPair builds the metrics of every experiment of the sweep.
+// Energy that could not be measured is reported as missing (NaN), the report fails only when
+// the node energy of every experiment is missing or when ctx is done.
+func GenExpMetricPair(ctx context.Context, c config.Config, logger *slog.Logger) (ExpMetricPair, error) {
+	if c.ReportConf.Discover {
+		return discoverExpMetricPair(ctx, c, logger)
+	}
+
 	// Mapping plot name (model_inputMean_outputMean) to a list of MetricPair
 	// One plot would have #concurrency metric
 	expMetricsPair := make(ExpMetricPair)
@@ -22,29 +55,414 @@ func GenExpMetricPair(c config.Config, logger *slog.Logger) (ExpMetricPair, erro
 	if err != nil {
 		return nil, err
 	}
+	roots, err := repetitionRoots(c.ReportConf.ArtfDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing repetitions in %s: %v", c.ReportConf.ArtfDir, err)
+	}
+	if len(roots) > 1 {
+		logger.Info("Found repetitions of the sweep", "count", len(roots), "dirs", roots)
+	}
 
-	// logging how many files need to parse
-	logger.Info("Start parsing GenAI-Perf experiment results", "count", len(paths))
+	source, err := NewEnergySource(ctx, c, logger)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := NewTokenizer(c.ReportConf.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSteadyState(c.ReportConf.SteadyState); err != nil {
+		return nil, err
+	}
+
+	// Every repetition of an experiment is a profile to parse, cells index them in sweep order
+	var (
+		cells      [][]int
+		repPaths   []string
+		repConfs   []GenAIPerfExpConf
+		repPartial []bool
+	)
+	checkpoint := c.GenAIPerf.Enabled.Checkpoint
 	for _, path := range paths {
-		profile, err := ParseGenAIPerfJSON(path, logger)
+		ec, err := GetConfFromPath(path)
 		if err != nil {
 			return nil, err
 		}
+		if err := ec.applyModelConf(c.ReportConf); err != nil {
+			return nil, err
+		}
+		ec.Stream = c.GenAIPerf.Enabled.Stream
 
-		ec, err := GetConfFromPath(path)
+		rel, err := filepath.Rel(c.ReportConf.ArtfDir, path)
 		if err != nil {
 			return nil, err
 		}
+		var cell []int
+		for _, root := range roots {
+			repPath, partial := filepath.Join(root, rel), false
+			if len(roots) > 1 || checkpoint {
+				// A repetition may have been interrupted or the experiment not be completed yet, use what is available
+				if repPath, partial, err = resolveProfile(repPath, checkpoint); err != nil {
+					if len(roots) > 1 {
+						logger.Warn("Repetition of the experiment is missing, aggregating the other ones",
+							"repetition", root, "profile", rel, "error", err)
+					}
+					continue
+				}
+			}
+			cell = append(cell, len(repPaths))
+			repPaths = append(repPaths, repPath)
+			repConfs = append(repConfs, ec)
+			repPartial = append(repPartial, partial)
+		}
+		if len(cell) == 0 {
+			if checkpoint {
+				logger.Info("Experiment not started yet", "profile", rel)
+				continue
+			}
+			return nil, fmt.Errorf("profile %s not found in any repetition", rel)
+		}
+		cells = append(cells, cell)
+	}
+	if len(repPaths) == 0 {
+		return nil, fmt.Errorf("no profile nor checkpoint found in %s", c.ReportConf.ArtfDir)
+	}
+
+	// logging how many files need to parse
+	logger.Info("Start parsing GenAI-Perf experiment results", "count", len(repPaths), "parallelism", c.ReportConf.Pipeline.Parallelism)
+	ems := make([]ExpMetrics, len(repPaths))
+	parsed := make([]bool, len(repPaths))
+	p := newProgress("Parsed profiles", len(repPaths), logger)
+	err = forEach(ctx, c.ReportConf.Pipeline.Parallelism, len(repPaths), func(ctx context.Context, i int) error {
+		defer p.step()
+		profile, err := ParseGenAIPerfJSON(repPaths[i], tok, logger)
+		if err == nil {
+			if err = checkProfile(profile); err != nil {
+				err = fmt.Errorf("invalid profile %s: %v", repPaths[i], err)
+			}
+		}
+		if err != nil && repPartial[i] {
+			// The checkpoint may be being written, the next one will be read
+			logger.Warn("Skipping unusable checkpoint", "file", repPaths[i], "error", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The payload tells what actually ran
+		if stream := streamed(profile.Experiments[0], repConfs[i].Stream); stream != repConfs[i].Stream {
+			logger.Warn("Streaming mode of the config differs from the profile, using the profile one",
+				"file", repPaths[i], "config", repConfs[i].Stream, "profile", stream)
+			repConfs[i].Stream = stream
+		}
+		ems[i] = perfExpMetrics(profile, repConfs[i], c.ReportConf.SteadyState, logger)
+		ems[i].Partial = repPartial[i]
+		ems[i].ExpectedReps = len(roots)
+		parsed[i] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Only one experiment in Custom GenAIPerf
-		pfm := ComputeMetrics(profile.Experiments[0], ec, logger)
-		pwm := GetPowerMetrics(profile.Experiments[0])
+	var (
+		measured      []int
+		measuredConfs []GenAIPerfExpConf
+		measuredEms   []ExpMetrics
+	)
+	for i := range repPaths {
+		if parsed[i] {
+			measured = append(measured, i)
+			measuredConfs = append(measuredConfs, repConfs[i])
+			measuredEms = append(measuredEms, ems[i])
+		}
+	}
+	if err := measureExperiments(ctx, c, source, measuredConfs, measuredEms, logger); err != nil {
+		return nil, fmt.Errorf("getting power metrics: %v", err)
+	}
+	for k, i := range measured {
+		ems[i] = measuredEms[k]
+	}
 
-		expMetricsPair[ec] = ExpMetrics{
-			PerfM:  pfm,
-			PowerM: pwm,
+	for _, cell := range cells {
+		// Repetitions run with another streaming mode are another experiment
+		reps := make(map[GenAIPerfExpConf][]ExpMetrics)
+		for _, i := range cell {
+			if parsed[i] {
+				reps[repConfs[i]] = append(reps[repConfs[i]], ems[i])
+			}
+		}
+		for ec, r := range reps {
+			expMetricsPair[ec] = aggregateReps(r)
 		}
 	}
 
+	if err := expMetricsPair.checkEnergy(); err != nil {
+		return nil, err
+	}
+	if err := applyIdleBaseline(ctx, c, expMetricsPair, source, logger); err != nil {
+		return nil, err
+	}
+	if err := applyFootprint(c, expMetricsPair, logger); err != nil {
+		return nil, err
+	}
 	return expMetricsPair, nil
 }
+
+// discoverExpMetricPair builds the metrics of every profile found under the artifacts directory.
+// Files that cannot be used are skipped with a warning, and when the config describes
+// a sweep the experiments it expects but that were not found are reported.
+func discoverExpMetricPair(ctx context.Context, c config.Config, logger *slog.Logger) (ExpMetricPair, error) {
+	expMetricsPair := make(ExpMetricPair)
+
+	paths, err := DiscoverProfiles(c.ReportConf.ArtfDir)
+	if err != nil {
+		return nil, err
+	}
+	if c.GenAIPerf.Enabled.Checkpoint {
+		// The experiments still running are read from their checkpoint
+		ckpts, err := discoverCheckpoints(c.ReportConf.ArtfDir, paths)
+		if err != nil {
+			return nil, err
+		}
+		if len(ckpts) > 0 {
+			logger.Info("Found checkpoints of running experiments", "count", len(ckpts))
+		}
+		paths = append(paths, ckpts...)
+	}
+
+	source, err := NewEnergySource(ctx, c, logger)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := NewTokenizer(c.ReportConf.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSteadyState(c.ReportConf.SteadyState); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Start parsing discovered GenAI-Perf experiment results", "dir", c.ReportConf.ArtfDir,
+		"count", len(paths), "parallelism", c.ReportConf.Pipeline.Parallelism)
+	// Profiles that cannot be used are left out of the results
+	type result struct {
+		ec        GenAIPerfExpConf
+		em        ExpMetrics
+		used      bool
+		layout    GenAIPerfExpConf
+		hasLayout bool
+	}
+	results := make([]result, len(paths))
+	p := newProgress("Parsed profiles", len(paths), logger)
+	err = forEach(ctx, c.ReportConf.Pipeline.Parallelism, len(paths), func(ctx context.Context, i int) error {
+		defer p.step()
+		path := paths[i]
+		profile, err := ParseGenAIPerfJSON(path, tok, logger)
+		if err != nil {
+			return nil
+		}
+		if err := checkProfile(profile); err != nil {
+			logger.Warn("Skipping invalid profile", "file", path, "error", err)
+			return nil
+		}
+
+		// The directory layout is what the sweep was configured with
+		if ec, err := GetConfFromPath(path); err == nil {
+			results[i].layout, results[i].hasLayout = ec, true
+		}
+		ec, err := discoverConf(path, profile, logger)
+		if err == nil {
+			err = ec.applyModelConf(c.ReportConf)
+		}
+		if err != nil {
+			logger.Warn("Skipping profile with unknown experiment config", "file", path, "error", err)
+			return nil
+		}
+		ec.Stream = streamed(profile.Experiments[0], c.GenAIPerf.Enabled.Stream)
+		results[i].ec, results[i].em, results[i].used = ec, perfExpMetrics(profile, ec, c.ReportConf.SteadyState, logger), true
+		results[i].em.Partial = isCheckpoint(path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[GenAIPerfExpConf]bool)
+	var (
+		confs []GenAIPerfExpConf
+		ems   []ExpMetrics
+	)
+	for _, r := range results {
+		if r.hasLayout {
+			found[r.layout] = true
+		}
+		if r.used {
+			confs = append(confs, r.ec)
+			ems = append(ems, r.em)
+		}
+	}
+	if err := measureExperiments(ctx, c, source, confs, ems, logger); err != nil {
+		return nil, fmt.Errorf("getting power metrics: %v", err)
+	}
+
+	// Profiles of the same experiment found in several directories are its repetitions
+	reps := make(map[GenAIPerfExpConf][]ExpMetrics)
+	used := 0
+	for _, r := range results {
+		if r.used {
+			reps[r.ec] = append(reps[r.ec], ems[used])
+			used++
+		}
+	}
+	for ec, r := range reps {
+		expMetricsPair[ec] = aggregateReps(r)
+	}
+
+	if len(expMetricsPair) == 0 {
+		return nil, fmt.Errorf("no usable GenAI-Perf profile found in %s", c.ReportConf.ArtfDir)
+	}
+	logger.Info("Discovered experiments", "count", len(expMetricsPair), "profiles", used, "skipped", len(paths)-used)
+	if err := expMetricsPair.checkEnergy(); err != nil {
+		logger.Warn("Reporting performance only", "error", err)
+	}
+	if err := applyIdleBaseline(ctx, c, expMetricsPair, source, logger); err != nil {
+		logger.Warn("Reporting gross energy only", "error", err)
+	}
+	if err := applyFootprint(c, expMetricsPair, logger); err != nil {
+		logger.Warn("Carbon and cost are unknown", "error", err)
+	}
+
+	// Only compare against the sweep when the config describes one
+	if len(c.GenAIPerf.Models) > 0 {
+		missing, err := missingCells(c, found)
+		partial := 0
+		for _, em := range expMetricsPair {
+			if em.Partial {
+				partial++
+			}
+		}
+		if err != nil {
+			logger.Warn("Cannot list the expected experiments", "error", err)
+		} else if len(missing) > 0 || partial > 0 {
+			for _, ec := range missing {
+				logger.Warn("Expected experiment not found", "model", ec.ServedModel(),
+					"input", ec.InputMean, "output", ec.OutputMean, "load", ec.LoadDesc(), "run_count", ec.RunCount)
+			}
+			logger.Warn("Sweep is incomplete", "missing", len(missing), "partial", partial, "found", len(expMetricsPair))
+		}
+	}
+
+	return expMetricsPair, nil
+}
+
+// perfExpMetrics computes the perf metrics of the experiment in profile over its steady-state window,
+// its energy and power are missing until measured
+func perfExpMetrics(profile *ProfileExport, ec GenAIPerfExpConf, sc config.SteadyStateConf, logger *slog.Logger) ExpMetrics {
+	// Only one experiment in Custom GenAIPerf
+	exp := profile.Experiments[0]
+	win, err := NewSteadyWindow(exp, sc)
+	if err != nil {
+		logger.Warn("No steady-state window, measuring the whole experiment", "model", ec.ServedModel(),
+			"input", ec.InputMean, "output", ec.OutputMean, "load", ec.LoadDesc(), "error", err)
+		win = fullWindow(exp)
+	}
+	return ExpMetrics{
+		PerfM:     ComputeMetrics(exp, win, ec, logger),
+		PowerM:    nanPowerMetrics(),
+		IdleM:     nanPowerMetrics(),
+		NetM:      nanPowerMetrics(),
+		Footprint: nanFootprint(),
+		PowerW:    newPowerSummary(nil),
+		Requests:  requestSpans(win.Requests),
+		Begin:     win.Begin,
+		End:       win.End,
+	}
+}
+
+// measureExperiment sets the energy and the power of the experiment in em from source.
+// The power series is fetched with a resolution of step when the source provides one, 0 skips it.
+// Energy that could not be measured is logged and left missing, an error is only returned when ctx is done.
+func measureExperiment(ctx context.Context, ec GenAIPerfExpConf, em *ExpMetrics, source EnergySource, step time.Duration, logger *slog.Logger) error {
+	// The per-model settings are keyed on the served model, which the payload may name otherwise
+	model := ec.ServedModel()
+	pwm, err := source.Energy(ctx, model, em.Begin, em.End)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		logger.Warn("Energy is missing, reported as N/A", "model", ec.ServedModel(), "input", ec.InputMean,
+			"output", ec.OutputMean, "load", ec.LoadDesc(), "error", err)
+	}
+	em.PowerM = pwm
+
+	if pss, ok := source.(PowerSeriesSource); ok && step > 0 {
+		series, err := pss.PowerSeries(ctx, model, em.Begin, em.End, step)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			logger.Warn("Cannot get the power series", "model", model, "error", err)
+			series = nil
+		}
+		em.PowerSeries, em.PowerW = series, newPowerSummary(series)
+	}
+	return nil
+}
+
+// checkEnergy fails when the node energy of every experiment is missing, e.g. when Prometheus is unreachable
+func (emp ExpMetricPair) checkEnergy() error {
+	for _, em := range emp {
+		if !math.IsNaN(em.PowerM.NodePlatformJ) {
+			return nil
+		}
+	}
+	if len(emp) == 0 {
+		return nil
+	}
+	return fmt.Errorf("node energy is missing for all %d experiments, see the warnings above", len(emp))
+}
+
+// EnergyPerToken returns the node platform energy per output token (unit: joules)
+func (m ExpMetrics) EnergyPerToken() float64 {
+	return m.PowerM.NodePlatformJ / float64(m.PerfM.TotalOutputTokens)
+}
+
+// NetEnergyPerToken returns the node platform energy net of idle per output token (unit: joules)
+func (m ExpMetrics) NetEnergyPerToken() float64 {
+	return m.NetM.NodePlatformJ / float64(m.PerfM.TotalOutputTokens)
+}
+
+// SortedConfs returns the experiment configs sorted by model, parameter size,
+// input length, output length, load (concurrency experiments first), run count and streaming mode
+func (emp ExpMetricPair) SortedConfs() []GenAIPerfExpConf {
+	confs := make([]GenAIPerfExpConf, 0, len(emp))
+	for ec := range emp {
+		confs = append(confs, ec)
+	}
+	sort.Slice(confs, func(i, j int) bool {
+		a, b := confs[i], confs[j]
+		switch {
+		case a.Model != b.Model:
+			return a.Model < b.Model
+		case a.PMSize != b.PMSize:
+			return a.PMSize < b.PMSize
+		case a.Tag != b.Tag:
+			return a.Tag < b.Tag
+		case a.InputMean != b.InputMean:
+			return a.InputMean < b.InputMean
+		case a.OutputMean != b.OutputMean:
+			return a.OutputMean < b.OutputMean
+		case a.LoadMode() != b.LoadMode():
+			return a.LoadMode() == LoadConcurrency
+		case a.Load() != b.Load():
+			return a.Load() < b.Load()
+		case a.RunCount != b.RunCount:
+			return a.RunCount < b.RunCount
+		default:
+			// Streaming first
+			return a.Stream && !b.Stream
+		}
+	})
+	return confs
+}
@@ -0,0 +1,156 @@
+package input
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// The experiments of a sweep are processed in stages, each one running on a bounded worker pool:
+// the profiles are parsed, the energy counters of the experiments close in time are prefetched
+// in batches, then each experiment is measured. Results are stored by index so the report
+// does not depend on the order the workers complete in.
+
+// forEach calls f with every index in [0, n) from at most parallelism goroutines.
+// The first error cancels the ctx passed to the other calls and is returned.
+func forEach(ctx context.Context, parallelism, n int, f func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	next := make(chan int)
+	for w := 0; w < min(max(parallelism, 1), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := f(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// progress logs the completion of a stage every tenth of its work
+type progress struct {
+	msg    string
+	total  int
+	done   atomic.Int64
+	start  time.Time
+	logger *slog.Logger
+}
+
+func newProgress(msg string, total int, logger *slog.Logger) *progress {
+	return &progress{msg: msg, total: total, start: time.Now(), logger: logger}
+}
+
+// step records one more unit of work done
+func (p *progress) step() {
+	done := int(p.done.Add(1))
+	if done == p.total || done*10/p.total != (done-1)*10/p.total {
+		p.logger.Info(p.msg, "done", done, "total", p.total, "elapsed", time.Since(p.start).Round(time.Millisecond))
+	}
+}
+
+// batchSource is an energy source able to fetch the energy of several experiments at once
+type batchSource interface {
+	// Prefetch fetches the energy of model over [begin, end] so the Energy of the experiments
+	// within it are computed without querying again
+	Prefetch(ctx context.Context, model string, begin, end time.Time) error
+}
+
+// energyBatch is the span of the experiments of a model whose energy is fetched at once
+type energyBatch struct {
+	model      string
+	begin, end time.Time
+}
+
+// energyBatches groups the experiments of each served model into batches spanning at most window
+func energyBatches(confs []GenAIPerfExpConf, ems []ExpMetrics, window time.Duration) []energyBatch {
+	byModel := make(map[string][]ExpMetrics)
+	for i, em := range ems {
+		byModel[confs[i].ServedModel()] = append(byModel[confs[i].ServedModel()], em)
+	}
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	var batches []energyBatch
+	for _, model := range models {
+		exps := byModel[model]
+		sort.Slice(exps, func(i, j int) bool { return exps[i].Begin.Before(exps[j].Begin) })
+		var cur *energyBatch
+		for _, em := range exps {
+			if cur == nil || em.End.Sub(cur.begin) > window {
+				batches = append(batches, energyBatch{model: model, begin: em.Begin, end: em.End})
+				cur = &batches[len(batches)-1]
+				continue
+			}
+			if em.End.After(cur.end) {
+				cur.end = em.End
+			}
+		}
+	}
+	return batches
+}
+
+// measureExperiments measures the energy and the power of the experiments of confs, whose perf metrics
+// and window are already in ems. The energy is prefetched in batches when the source supports it.
+func measureExperiments(ctx context.Context, c config.Config, source EnergySource, confs []GenAIPerfExpConf, ems []ExpMetrics, logger *slog.Logger) error {
+	pc := c.ReportConf.Pipeline
+	if bs, ok := source.(batchSource); ok && pc.BatchWindow > 0 {
+		batches := energyBatches(confs, ems, pc.BatchWindow)
+		logger.Info("Prefetching the energy counters", "experiments", len(ems), "batches", len(batches))
+		err := forEach(ctx, pc.Parallelism, len(batches), func(ctx context.Context, i int) error {
+			b := batches[i]
+			if err := bs.Prefetch(ctx, b.model, b.begin, b.end); err != nil {
+				// The experiments of the batch query their own energy instead
+				logger.Warn("Cannot prefetch the energy", "model", b.model, "begin", b.begin, "end", b.end, "error", err)
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	p := newProgress("Measured experiments", len(ems), logger)
+	return forEach(ctx, pc.Parallelism, len(ems), func(ctx context.Context, i int) error {
+		if err := measureExperiment(ctx, confs[i], &ems[i], source, c.ReportConf.PowerSeries.Step, logger); err != nil {
+			return err
+		}
+		p.step()
+		return nil
+	})
+}
@@ -0,0 +1,203 @@
+package input
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/internal/client/promclient"
+)
+
+// PowerSample is a single power reading (unit: watts)
+type PowerSample struct {
+	Time  time.Time
+	Watts float64
+}
+
+// PowerSeriesSource is implemented by the energy sources able to tell the power drawn over time
+type PowerSeriesSource interface {
+	// PowerSeries returns the power of each component, named as in promclient.KeplerComponents,
+	// within [begin, end] with a resolution of about step
+	PowerSeries(ctx context.Context, model string, begin, end time.Time, step time.Duration) (map[string][]PowerSample, error)
+}
+
+// PowerStats summarizes the power drawn by a component during an experiment (unit: watts), NaN if unknown
+type PowerStats struct {
+	AvgW  float64 `json:"avg_w"`
+	PeakW float64 `json:"peak_w"`
+	P95W  float64 `json:"p95_w"`
+}
+
+// PowerSummary holds the power statistics of the main components
+type PowerSummary struct {
+	NodePlatform PowerStats `json:"node_platform"`
+	NodeGPU      PowerStats `json:"node_gpu"`
+	NodePackage  PowerStats `json:"node_package"`
+	PodPlatform  PowerStats `json:"pod_platform"`
+}
+
+// RequestSpan is the time a request was in flight, from its start to its last response
+type RequestSpan struct {
+	Start time.Time
+	End   time.Time
+}
+
+// summarizePower computes the statistics of a power series
+func summarizePower(samples []PowerSample) PowerStats {
+	if len(samples) == 0 {
+		return PowerStats{AvgW: math.NaN(), PeakW: math.NaN(), P95W: math.NaN()}
+	}
+	watts := make([]float64, len(samples))
+	var sum float64
+	for i, s := range samples {
+		watts[i] = s.Watts
+		sum += s.Watts
+	}
+	sort.Float64s(watts)
+	return PowerStats{
+		AvgW:  sum / float64(len(watts)),
+		PeakW: watts[len(watts)-1],
+		P95W:  percentile(watts, 95),
+	}
+}
+
+// newPowerSummary summarizes the power series of the main components
+func newPowerSummary(series map[string][]PowerSample) PowerSummary {
+	return PowerSummary{
+		NodePlatform: summarizePower(series[promclient.NodePlatform]),
+		NodeGPU:      summarizePower(series[promclient.NodeGPU]),
+		NodePackage:  summarizePower(series[promclient.NodePackage]),
+		PodPlatform:  summarizePower(series[promclient.PodPlatform]),
+	}
+}
+
+// requestSpans returns the spans of the answered requests of an experiment
+func requestSpans(reqs []Request) []RequestSpan {
+	var spans []RequestSpan
+	for _, req := range reqs {
+		if len(req.ResponseTimestamps) == 0 {
+			continue
+		}
+		spans = append(spans, RequestSpan{
+			Start: time.Unix(0, req.Timestamp),
+			End:   time.Unix(0, req.ResponseTimestamps[len(req.ResponseTimestamps)-1]),
+		})
+	}
+	return spans
+}
+
+// PowerSeries queries the rate of each Kepler counter over [begin, end]
+func (ks *keplerSource) PowerSeries(ctx context.Context, model string, begin, end time.Time, step time.Duration) (map[string][]PowerSample, error) {
+	nodeMatchers, pod := keplerSelector(ks.rc.KeplerSelectorFor(model))
+	queries, err := ks.schema.Queries(nodeMatchers, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string][]PowerSample)
+	for _, q := range queries {
+		resp := promclient.QueryRangeSum(ctx, promclient.RateQuery(q.Query, ks.rc.PowerSeries.RateWindow), begin, end, step)
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		if len(resp.Warnings) > 0 {
+			ks.logger.Warn("Prometheus returned warnings", "query", resp.Results[0].Name, "warnings", resp.Warnings)
+		}
+		for _, sp := range resp.Results[0].Samples {
+			series[q.Component] = append(series[q.Component], PowerSample{Time: sp.Timestamp.Time(), Watts: float64(sp.Value)})
+		}
+	}
+	for component, parts := range ks.schema.Fallbacks {
+		if len(series[component]) > 0 {
+			continue
+		}
+		// The range queries share their steps
+		watts := make(map[int64]float64)
+		complete := true
+		for _, part := range parts {
+			complete = complete && len(series[part]) > 0
+			for _, s := range series[part] {
+				watts[s.Time.UnixNano()] += s.Watts
+			}
+		}
+		if !complete {
+			continue
+		}
+		for _, s := range series[parts[0]] {
+			series[component] = append(series[component], PowerSample{Time: s.Time, Watts: watts[s.Time.UnixNano()]})
+		}
+	}
+	return series, nil
+}
+
+// PowerSeries returns the logged power samples within [begin, end], the step is ignored
+func (ps *powerLogSource) PowerSeries(_ context.Context, _ string, begin, end time.Time, _ time.Duration) (map[string][]PowerSample, error) {
+	series := make(map[string][]PowerSample)
+	for component, samples := range ps.series {
+		for _, s := range samples {
+			if !s.Time.Before(begin) && !s.Time.After(end) {
+				series[component] = append(series[component], s)
+			}
+		}
+	}
+	return series, nil
+}
+
+// PowerSeries derives the power from consecutive RAPL readings within [begin, end], the step is ignored.
+// Zones are mapped onto components as in Energy.
+func (rs *raplSource) PowerSeries(_ context.Context, _ string, begin, end time.Time, _ time.Duration) (map[string][]PowerSample, error) {
+	// Power of each component by unix nanosecond
+	sums := make(map[string]map[int64]float64)
+	add := func(component string, ts int64, watts float64) {
+		if sums[component] == nil {
+			sums[component] = make(map[int64]float64)
+		}
+		sums[component][ts] += watts
+	}
+
+	var hasPsys bool
+	for zone, samples := range rs.zones {
+		name := rs.names[zone]
+		for i := 1; i < len(samples); i++ {
+			ts := samples[i].Timestamp.Time()
+			if ts.Before(begin) || ts.After(end) {
+				continue
+			}
+			ns := ts.UnixNano()
+			dt := samples[i].Timestamp.Sub(samples[i-1].Timestamp).Seconds()
+			if dt <= 0 {
+				continue
+			}
+			watts := float64(samples[i].Value-samples[i-1].Value) / dt
+			switch {
+			case strings.HasPrefix(name, "package"):
+				add(promclient.NodePackage, ns, watts)
+			case strings.HasPrefix(name, "dram"):
+				add(promclient.NodeDRAM, ns, watts)
+			case strings.HasPrefix(name, "psys"):
+				add(promclient.NodePlatform, ns, watts)
+				hasPsys = true
+			}
+		}
+	}
+	if !hasPsys {
+		// Readings of all zones are recorded at the same time
+		for _, component := range []string{promclient.NodePackage, promclient.NodeDRAM} {
+			for ts, watts := range sums[component] {
+				add(promclient.NodePlatform, ts, watts)
+			}
+		}
+	}
+
+	series := make(map[string][]PowerSample)
+	for component, byTime := range sums {
+		for ts, watts := range byTime {
+			series[component] = append(series[component], PowerSample{Time: time.Unix(0, ts), Watts: watts})
+		}
+		samples := series[component]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	}
+	return series, nil
+}
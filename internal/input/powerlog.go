@@ -0,0 +1,203 @@
+package input
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/internal/client/promclient"
+)
+
+// powerLogSource reads a generic CSV power log with a timestamp column and
+// one power column (unit: watts) per component named as in promclient.KeplerComponents, e.g.
+//
+//	timestamp,node_platform,node_gpu
+//	2025-08-01T10:00:00Z,182.5,75.1
+//
+// Components without a column are left as 0.
+type powerLogSource struct {
+	series map[string][]PowerSample
+}
+
+func newPowerLogSource(path string, columns map[string]string) (*powerLogSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening power log %s: %v", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading power log header from %s: %v", path, err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.TrimSpace(h)] = i
+	}
+	tsCol, ok := cols["timestamp"]
+	if !ok {
+		return nil, fmt.Errorf("power log %s is missing column timestamp", path)
+	}
+	for component, column := range columns {
+		if _, ok := cols[column]; !ok {
+			return nil, fmt.Errorf("power log %s is missing column %s for %s", path, column, component)
+		}
+	}
+
+	// Map each component onto its column index
+	componentCols := make(map[string]int)
+	for _, component := range promclient.KeplerComponents {
+		column := component
+		if c, ok := columns[component]; ok {
+			column = c
+		}
+		if idx, ok := cols[column]; ok {
+			componentCols[component] = idx
+		}
+	}
+	if len(componentCols) == 0 {
+		return nil, fmt.Errorf("power log %s has no component column", path)
+	}
+
+	series := make(map[string][]PowerSample)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading power log %s: %v", path, err)
+		}
+		ts, err := parseTimestamp(record[tsCol])
+		if err != nil {
+			return nil, fmt.Errorf("parsing power log %s: %v", path, err)
+		}
+		for component, idx := range componentCols {
+			value := strings.TrimSpace(record[idx])
+			if value == "" {
+				continue
+			}
+			watts, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s in power log %s: %v", component, path, err)
+			}
+			series[component] = append(series[component], PowerSample{Time: ts, Watts: watts})
+		}
+	}
+	for _, samples := range series {
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	}
+
+	return &powerLogSource{series: series}, nil
+}
+
+// Energy integrates the power of each component over [begin, end].
+// Components whose samples do not span the window are NaN and returned along with an error.
+func (ps *powerLogSource) Energy(_ context.Context, _ string, begin, end time.Time) (KeplerPowerMetrics, error) {
+	var pm KeplerPowerMetrics
+	var failures []string
+	for component, samples := range ps.series {
+		energy, err := integratePower(samples, begin, end)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", component, err))
+		}
+		pm.setComponent(component, energy)
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return pm, fmt.Errorf("%d of %d power log components missing: %s", len(failures), len(ps.series), strings.Join(failures, "; "))
+	}
+	return pm, nil
+}
+
+// integratePower integrates power samples over [begin, end] with the trapezoidal rule (unit: joules).
+// The power is linearly interpolated at the window edges.
+// The energy is NaN when the samples do not span the window, along with an error naming the missing range.
+func integratePower(samples []PowerSample, begin, end time.Time) (float64, error) {
+	if len(samples) == 0 {
+		return math.NaN(), fmt.Errorf("no sample for [%s, %s]", formatTime(begin), formatTime(end))
+	}
+	if err := checkCoverage(samples[0].Time, samples[len(samples)-1].Time, begin, end); err != nil {
+		return math.NaN(), err
+	}
+
+	var energy float64
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		dt := cur.Time.Sub(prev.Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		a := math.Max(float64(prev.Time.UnixNano()), float64(begin.UnixNano()))
+		b := math.Min(float64(cur.Time.UnixNano()), float64(end.UnixNano()))
+		if a >= b {
+			continue
+		}
+		wattsAt := func(ns float64) float64 {
+			frac := (ns - float64(prev.Time.UnixNano())) / 1e9 / dt
+			return prev.Watts + (cur.Watts-prev.Watts)*frac
+		}
+		energy += (wattsAt(a) + wattsAt(b)) / 2 * (b - a) / 1e9
+	}
+	return energy, nil
+}
+
+// checkCoverage returns an error naming the part of [begin, end] outside the samples spanning [first, last],
+// nil when they cover the window
+func checkCoverage(first, last, begin, end time.Time) error {
+	var missing []string
+	if first.After(begin) && !first.After(end) {
+		missing = append(missing, fmt.Sprintf("[%s, %s]", formatTime(begin), formatTime(first)))
+	}
+	if last.Before(end) && !last.Before(begin) {
+		missing = append(missing, fmt.Sprintf("[%s, %s]", formatTime(last), formatTime(end)))
+	}
+	if first.After(end) || last.Before(begin) {
+		// No sample around the window
+		missing = append(missing, fmt.Sprintf("[%s, %s]", formatTime(begin), formatTime(end)))
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("samples span [%s, %s], missing %s", formatTime(first), formatTime(last), strings.Join(missing, " and "))
+}
+
+// formatTime formats t for the messages about sample coverage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTimestamp parses an RFC3339 timestamp or a unix timestamp,
+// whose unit (s, ms, us or ns) is inferred from its magnitude
+func parseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	switch {
+	case v < 1e11:
+		return time.Unix(0, int64(v*1e9)), nil
+	case v < 1e14:
+		return time.Unix(0, int64(v*1e6)), nil
+	case v < 1e17:
+		return time.Unix(0, int64(v*1e3)), nil
+	default:
+		// Parse integers directly to keep the nanosecond precision
+		if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(0, ns), nil
+		}
+		return time.Unix(0, int64(v)), nil
+	}
+}
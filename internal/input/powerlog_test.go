@@ -0,0 +1,125 @@
+package input
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var logStart = time.Unix(1_700_000_000, 0)
+
+// constantPower returns a sample of watts every second over [logStart+from, logStart+to] (unit: seconds)
+func constantPower(watts float64, from, to int) []PowerSample {
+	var samples []PowerSample
+	for s := from; s <= to; s++ {
+		samples = append(samples, PowerSample{Time: logStart.Add(time.Duration(s) * time.Second), Watts: watts})
+	}
+	return samples
+}
+
+// since returns the time sec seconds after logStart
+func since(sec float64) time.Time {
+	return logStart.Add(time.Duration(sec * float64(time.Second)))
+}
+
+func TestIntegratePower(t *testing.T) {
+	ramp := []PowerSample{{Time: since(0), Watts: 100}, {Time: since(10), Watts: 200}}
+	tests := []struct {
+		name       string
+		samples    []PowerSample
+		begin, end float64 // Seconds after logStart
+		want       float64
+		missing    string // Missing range named in the error, empty if the window is covered
+	}{
+		{name: "covered", samples: constantPower(100, 0, 10), begin: 2, end: 8, want: 600},
+		{name: "covered to the edges", samples: constantPower(100, 0, 10), begin: 0, end: 10, want: 1000},
+		{name: "edges interpolated", samples: ramp, begin: 5, end: 10, want: 875},
+		{
+			name:    "log ends within the window",
+			samples: constantPower(100, 0, 10), begin: 5, end: 15,
+			missing: "[2023-11-14T22:13:30Z, 2023-11-14T22:13:35Z]",
+		},
+		{
+			name:    "log starts within the window",
+			samples: constantPower(100, 5, 10), begin: 0, end: 8,
+			missing: "[2023-11-14T22:13:20Z, 2023-11-14T22:13:25Z]",
+		},
+		{
+			name:    "log within the window",
+			samples: constantPower(100, 2, 8), begin: 0, end: 10,
+			missing: "[2023-11-14T22:13:20Z, 2023-11-14T22:13:22Z] and [2023-11-14T22:13:28Z, 2023-11-14T22:13:30Z]",
+		},
+		{
+			name:    "log before the window",
+			samples: constantPower(100, 0, 10), begin: 20, end: 30,
+			missing: "[2023-11-14T22:13:40Z, 2023-11-14T22:13:50Z]",
+		},
+		{
+			name:  "no sample",
+			begin: 0, end: 10,
+			missing: "[2023-11-14T22:13:20Z, 2023-11-14T22:13:30Z]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := integratePower(tt.samples, since(tt.begin), since(tt.end))
+			if tt.missing == "" {
+				if err != nil {
+					t.Fatalf("integratePower failed: %v", err)
+				}
+				if math.Abs(got-tt.want) > 1e-6 {
+					t.Errorf("integratePower = %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.missing) {
+				t.Errorf("integratePower error = %v, want one naming %s", err, tt.missing)
+			}
+			if !math.IsNaN(got) {
+				t.Errorf("integratePower = %v, want NaN", got)
+			}
+		})
+	}
+}
+
+func TestPowerLogSourceEnergy(t *testing.T) {
+	// The GPU column stops being recorded after 5s
+	var log strings.Builder
+	log.WriteString("timestamp,node_platform,gpu_w\n")
+	for s := 0; s <= 10; s++ {
+		gpu := ""
+		if s <= 5 {
+			gpu = "50"
+		}
+		log.WriteString(since(float64(s)).Format(time.RFC3339) + ",200," + gpu + "\n")
+	}
+	path := filepath.Join(t.TempDir(), "power.csv")
+	if err := os.WriteFile(path, []byte(log.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := newPowerLogSource(path, map[string]string{"node_gpu": "gpu_w"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pm, err := ps.Energy(context.Background(), "", since(1), since(5))
+	if err != nil {
+		t.Fatalf("Energy over a covered window failed: %v", err)
+	}
+	if pm.NodePlatformJ != 800 || pm.NodeGPUJ != 200 {
+		t.Errorf("Energy = platform %v J, GPU %v J, want 800 J, 200 J", pm.NodePlatformJ, pm.NodeGPUJ)
+	}
+
+	// Only the component missing part of the window is missing
+	pm, err = ps.Energy(context.Background(), "", since(2), since(8))
+	if err == nil || !strings.Contains(err.Error(), "node_gpu") {
+		t.Errorf("Energy error = %v, want one naming node_gpu", err)
+	}
+	if pm.NodePlatformJ != 1200 || !math.IsNaN(pm.NodeGPUJ) {
+		t.Errorf("Energy = platform %v J, GPU %v J, want 1200 J, NaN", pm.NodePlatformJ, pm.NodeGPUJ)
+	}
+}
@@ -0,0 +1,150 @@
+package input
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/explorerray/itpe-report/internal/client/promclient"
+	"github.com/prometheus/common/model"
+)
+
+// raplSource reads RAPL energy counters recorded from /sys/class/powercap into a CSV file
+// with the header: timestamp,zone,name,energy_uj,max_energy_range_uj
+// where zone is the powercap directory (e.g. intel-rapl:0) and name the content of its name file.
+// See hack/record-rapl.sh for a recorder.
+type raplSource struct {
+	// Unwrapped energy samples (unit: joules) of each zone
+	zones map[string][]model.SamplePair
+	// Zone name (e.g. package-0, dram, psys) of each zone
+	names map[string]string
+}
+
+type raplReading struct {
+	ts       time.Time
+	energyUJ float64
+	maxUJ    float64
+}
+
+func newRAPLSource(path string) (*raplSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening RAPL log %s: %v", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading RAPL log header from %s: %v", path, err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.TrimSpace(h)] = i
+	}
+	for _, required := range []string{"timestamp", "zone", "name", "energy_uj"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("RAPL log %s is missing column %s", path, required)
+		}
+	}
+	maxCol, hasMax := cols["max_energy_range_uj"]
+
+	readings := make(map[string][]raplReading)
+	names := make(map[string]string)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading RAPL log %s: %v", path, err)
+		}
+		ts, err := parseTimestamp(record[cols["timestamp"]])
+		if err != nil {
+			return nil, fmt.Errorf("parsing RAPL log %s: %v", path, err)
+		}
+		energy, err := strconv.ParseFloat(strings.TrimSpace(record[cols["energy_uj"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing energy_uj in RAPL log %s: %v", path, err)
+		}
+		var maxUJ float64
+		if hasMax {
+			maxUJ, _ = strconv.ParseFloat(strings.TrimSpace(record[maxCol]), 64)
+		}
+		zone := strings.TrimSpace(record[cols["zone"]])
+		names[zone] = strings.TrimSpace(record[cols["name"]])
+		readings[zone] = append(readings[zone], raplReading{ts: ts, energyUJ: energy, maxUJ: maxUJ})
+	}
+
+	zones := make(map[string][]model.SamplePair)
+	for zone, rs := range readings {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].ts.Before(rs[j].ts) })
+		// energy_uj wraps around at max_energy_range_uj, unwrap it into a monotonic counter
+		var offset float64
+		samples := make([]model.SamplePair, len(rs))
+		for i, rd := range rs {
+			if i > 0 && rd.energyUJ < rs[i-1].energyUJ {
+				if rd.maxUJ > 0 {
+					offset += rd.maxUJ
+				} else {
+					offset += rs[i-1].energyUJ
+				}
+			}
+			samples[i] = model.SamplePair{
+				Timestamp: model.TimeFromUnixNano(rd.ts.UnixNano()),
+				Value:     model.SampleValue((rd.energyUJ + offset) / 1e6),
+			}
+		}
+		zones[zone] = samples
+	}
+
+	return &raplSource{zones: zones, names: names}, nil
+}
+
+// Energy integrates the RAPL zones over [begin, end].
+// core/uncore subzones are already accounted in their package and are skipped.
+// Without a psys zone, the platform energy falls back to package + DRAM.
+// Components with a zone whose samples do not span the window are NaN and returned along with an error.
+func (rs *raplSource) Energy(_ context.Context, _ string, begin, end time.Time) (KeplerPowerMetrics, error) {
+	var pm KeplerPowerMetrics
+	var hasPsys bool
+	var failures []string
+	for zone, samples := range rs.zones {
+		name := rs.names[zone]
+		if !strings.HasPrefix(name, "package") && !strings.HasPrefix(name, "dram") && !strings.HasPrefix(name, "psys") {
+			continue
+		}
+		first, last := samples[0].Timestamp.Time(), samples[len(samples)-1].Timestamp.Time()
+		increase := promclient.IncreaseOverWindow(samples, begin, end)
+		// Sample timestamps are in milliseconds, so is the window checked against them
+		from, to := model.TimeFromUnixNano(begin.UnixNano()).Time(), model.TimeFromUnixNano(end.UnixNano()).Time()
+		if err := checkCoverage(first, last, from, to); err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s): %v", zone, name, err))
+			increase = math.NaN()
+		}
+		switch {
+		case strings.HasPrefix(name, "package"):
+			pm.NodePackageJ += increase
+		case strings.HasPrefix(name, "dram"):
+			pm.NodeDRAMJ += increase
+		case strings.HasPrefix(name, "psys"):
+			pm.NodePlatformJ += increase
+			hasPsys = true
+		}
+	}
+	if !hasPsys {
+		pm.NodePlatformJ = pm.NodePackageJ + pm.NodeDRAMJ
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return pm, fmt.Errorf("%d RAPL zones missing: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return pm, nil
+}
@@ -0,0 +1,59 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRAPLSourceEnergy(t *testing.T) {
+	// A package drawing 50 W and a DRAM drawing 5 W sampled every second for 10s,
+	// the package counter wrapping around at 300 J
+	var log strings.Builder
+	log.WriteString("timestamp,zone,name,energy_uj,max_energy_range_uj\n")
+	for s := 0; s <= 10; s++ {
+		ts := since(float64(s)).UnixNano()
+		fmt.Fprintf(&log, "%d,intel-rapl:0,package-0,%d,300000000\n", ts, (s*50_000_000)%300_000_000)
+		fmt.Fprintf(&log, "%d,intel-rapl:0:0,core,%d,300000000\n", ts, s*40_000_000)
+		fmt.Fprintf(&log, "%d,intel-rapl:0:1,dram,%d,300000000\n", ts, s*5_000_000)
+	}
+	path := filepath.Join(t.TempDir(), "rapl.csv")
+	if err := os.WriteFile(path, []byte(log.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := newRAPLSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		begin, end float64 // Seconds after logStart
+		want       float64 // Platform energy, package + DRAM without psys
+		wantErr    bool
+	}{
+		{name: "covered", begin: 2.5, end: 8.5, want: 330},
+		{name: "covered to the edges", begin: 0, end: 10, want: 550},
+		{name: "partly covered", begin: 5, end: 15, want: math.NaN(), wantErr: true},
+		{name: "no sample", begin: 20, end: 30, want: math.NaN(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, err := rs.Energy(context.Background(), "", since(tt.begin), since(tt.end))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Energy error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "missing [") {
+				t.Errorf("Energy error = %v, want one naming the missing range", err)
+			}
+			got := pm.NodePlatformJ
+			if math.IsNaN(got) != math.IsNaN(tt.want) || math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("platform energy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package input
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// Repetitions of a sweep are stored side by side in the artifacts directory, each in its own
+// directory holding the experiment directories, e.g. rep1/, rep2/ or 20250801T1200/.
+// Experiment directories directly under the artifacts directory form a repetition as well.
+
+// tCritical95 holds the two-sided 95% critical values of the Student's t distribution by degrees of freedom
+var tCritical95 = []float64{
+	math.NaN(), 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// RepStats summarizes a metric over the repetitions of an experiment
+type RepStats struct {
+	N      int
+	Mean   float64
+	Stddev float64 // Sample standard deviation, 0 with a single repetition
+	CI95   float64 // Half width of the 95% confidence interval of the mean, 0 with a single repetition
+}
+
+// NewRepStats computes the mean, the sample standard deviation and the 95% confidence interval of values
+func NewRepStats(values []float64) RepStats {
+	rs := RepStats{N: len(values)}
+	if rs.N == 0 {
+		rs.Mean = math.NaN()
+		return rs
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	rs.Mean = sum / float64(rs.N)
+	if rs.N == 1 {
+		return rs
+	}
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - rs.Mean) * (v - rs.Mean)
+	}
+	rs.Stddev = math.Sqrt(sq / float64(rs.N-1))
+	t := 1.96
+	if df := rs.N - 1; df < len(tCritical95) {
+		t = tCritical95[df]
+	}
+	rs.CI95 = t * rs.Stddev / math.Sqrt(float64(rs.N))
+	return rs
+}
+
+// Repetitions returns the number of repetitions the metrics were aggregated from
+func (m ExpMetrics) Repetitions() int {
+	if len(m.Reps) == 0 {
+		return 1
+	}
+	return len(m.Reps)
+}
+
+// ExpectedRepetitions returns the number of repetitions of the sweep the experiment was expected in,
+// at least the ones it was aggregated from
+func (m ExpMetrics) ExpectedRepetitions() int {
+	return max(m.ExpectedReps, m.Repetitions())
+}
+
+// Stats summarizes value over the repetitions of the experiment
+func (m ExpMetrics) Stats(value func(ExpMetrics) float64) RepStats {
+	if len(m.Reps) == 0 {
+		return NewRepStats([]float64{value(m)})
+	}
+	values := make([]float64, len(m.Reps))
+	for i, rep := range m.Reps {
+		values[i] = value(rep)
+	}
+	return NewRepStats(values)
+}
+
+// aggregateReps merges the repetitions of an experiment into their mean.
+// Integer metrics are rounded, non-numeric fields are taken from the first repetition
+// and the experiment is partial when any repetition is.
+func aggregateReps(reps []ExpMetrics) ExpMetrics {
+	if len(reps) == 1 {
+		return reps[0]
+	}
+	agg := reps[0]
+	values := make([]reflect.Value, len(reps))
+	for i := range reps {
+		values[i] = reflect.ValueOf(&reps[i]).Elem()
+	}
+	meanFields(reflect.ValueOf(&agg).Elem(), values)
+	agg.Reps = reps
+	for _, rep := range reps {
+		agg.Partial = agg.Partial || rep.Partial
+	}
+	return agg
+}
+
+// updateReps applies f to every repetition of every experiment and aggregates them again
+func (emp ExpMetricPair) updateReps(f func(ec GenAIPerfExpConf, m *ExpMetrics)) {
+	for ec, em := range emp {
+		if len(em.Reps) == 0 {
+			f(ec, &em)
+			emp[ec] = em
+			continue
+		}
+		for i := range em.Reps {
+			f(ec, &em.Reps[i])
+		}
+		emp[ec] = aggregateReps(em.Reps)
+	}
+}
+
+// meanFields sets every numeric field of dst to the mean of the same field in srcs
+func meanFields(dst reflect.Value, srcs []reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Struct:
+			sub := make([]reflect.Value, len(srcs))
+			for j, src := range srcs {
+				sub[j] = src.Field(i)
+			}
+			meanFields(field, sub)
+		case reflect.Float64:
+			var sum float64
+			for _, src := range srcs {
+				sum += src.Field(i).Float()
+			}
+			field.SetFloat(sum / float64(len(srcs)))
+		case reflect.Int, reflect.Int64:
+			var sum float64
+			for _, src := range srcs {
+				sum += float64(src.Field(i).Int())
+			}
+			field.SetInt(int64(math.Round(sum / float64(len(srcs)))))
+		}
+	}
+}
+
+// repetitionRoots returns the directories of the repetitions of the sweep under artfDir, sorted by name
+func repetitionRoots(artfDir string) ([]string, error) {
+	entries, err := os.ReadDir(artfDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	hasExpDirs := false
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := ParseExpDir(e.Name()); err == nil {
+			hasExpDirs = true
+			continue
+		}
+		if containsExpDirs(filepath.Join(artfDir, e.Name())) {
+			roots = append(roots, filepath.Join(artfDir, e.Name()))
+		}
+	}
+	sort.Strings(roots)
+	if hasExpDirs || len(roots) == 0 {
+		roots = append([]string{artfDir}, roots...)
+	}
+	return roots, nil
+}
+
+// containsExpDirs tells whether dir holds at least one experiment directory
+func containsExpDirs(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if _, err := ParseExpDir(e.Name()); e.IsDir() && err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRepStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   RepStats
+	}{
+		{"none", nil, RepStats{Mean: math.NaN()}},
+		{"single repetition", []float64{5}, RepStats{N: 1, Mean: 5}},
+		{"two repetitions", []float64{1, 3}, RepStats{N: 2, Mean: 2, Stddev: math.Sqrt2, CI95: 12.706}},
+		{"three repetitions", []float64{1, 2, 3}, RepStats{N: 3, Mean: 2, Stddev: 1, CI95: 4.303 / math.Sqrt(3)}},
+		{"identical repetitions", []float64{4, 4, 4}, RepStats{N: 3, Mean: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewRepStats(tt.values)
+			if got.N != tt.want.N {
+				t.Errorf("N = %d, want %d", got.N, tt.want.N)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"Mean", got.Mean, tt.want.Mean},
+				{"Stddev", got.Stddev, tt.want.Stddev},
+				{"CI95", got.CI95, tt.want.CI95},
+			} {
+				if math.IsNaN(f.got) != math.IsNaN(f.want) || math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateReps(t *testing.T) {
+	rep := func(requests int, energy float64, partial bool) ExpMetrics {
+		var m ExpMetrics
+		m.PerfM.Model = "mistral"
+		m.PerfM.NumRequests = requests
+		m.PowerM.NodePlatformJ = energy
+		m.Partial = partial
+		m.ExpectedReps = 3
+		return m
+	}
+
+	single := aggregateReps([]ExpMetrics{rep(4, 100, false)})
+	if single.Repetitions() != 1 || single.Reps != nil || single.PowerM.NodePlatformJ != 100 {
+		t.Errorf("aggregateReps of a single repetition = %+v, want the repetition itself", single)
+	}
+	if ci := single.Stats(func(m ExpMetrics) float64 { return m.PowerM.NodePlatformJ }).CI95; ci != 0 {
+		t.Errorf("CI95 of a single repetition = %v, want 0", ci)
+	}
+
+	agg := aggregateReps([]ExpMetrics{rep(3, 100, false), rep(4, 200, true)})
+	if agg.PerfM.NumRequests != 4 || agg.PowerM.NodePlatformJ != 150 {
+		t.Errorf("aggregateReps = %d requests, %v J, want the mean 4 requests (rounded), 150 J",
+			agg.PerfM.NumRequests, agg.PowerM.NodePlatformJ)
+	}
+	if agg.PerfM.Model != "mistral" || !agg.Partial {
+		t.Errorf("aggregateReps = model %q, partial %v, want mistral, partial", agg.PerfM.Model, agg.Partial)
+	}
+	if agg.Repetitions() != 2 || agg.ExpectedRepetitions() != 3 {
+		t.Errorf("aggregateReps = %d/%d repetitions, want 2/3", agg.Repetitions(), agg.ExpectedRepetitions())
+	}
+	if ci := agg.Stats(func(m ExpMetrics) float64 { return m.PowerM.NodePlatformJ }).CI95; math.Abs(ci-12.706*50) > 1e-9 {
+		t.Errorf("CI95 = %v, want %v", ci, 12.706*50)
+	}
+}
+
+func TestGenExpMetricPairMissingRepetition(t *testing.T) {
+	// Two repetitions of the checkpoint fixture, the second one lacking the profile of concurrency 2
+	dir := t.TempDir()
+	for _, rep := range []string{"rep1", "rep2"} {
+		if err := os.Rename(copyFixture(t, func(name string) string { return name }), filepath.Join(dir, rep)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "rep2", "mistral-150-50-concurrency2", "4_2_profile.json")); err != nil {
+		t.Fatal(err)
+	}
+	c := checkpointConf(dir)
+	c.ReportConf.EnergySource.Path = filepath.Join(dir, "rep1", "power.csv")
+
+	var logs bytes.Buffer
+	emp, err := GenExpMetricPair(context.Background(), c, slog.New(slog.NewTextHandler(&logs, nil)))
+	if err != nil {
+		t.Fatalf("GenExpMetricPair failed: %v", err)
+	}
+	for ec, em := range emp {
+		wantReps := 2
+		if ec.Concurrency == 2 {
+			wantReps = 1
+		}
+		if em.Repetitions() != wantReps || em.ExpectedRepetitions() != 2 {
+			t.Errorf("concurrency %d: %d/%d repetitions, want %d/2", ec.Concurrency, em.Repetitions(), em.ExpectedRepetitions(), wantReps)
+		}
+	}
+	if !strings.Contains(logs.String(), "Repetition of the experiment is missing") || !strings.Contains(logs.String(), "rep2") {
+		t.Errorf("missing repetition not logged:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,183 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// Usage is the token usage reported by the server for a request
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Tokenizer counts the tokens of a text, used when the server does not report them.
+// The chunks of a streamed response are joined before being counted.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// NewTokenizer creates the tokenizer selected in the report config
+func NewTokenizer(name string) (Tokenizer, error) {
+	switch name {
+	case "", config.TokenizerApprox:
+		return approxTokenizer{}, nil
+	case config.TokenizerWords:
+		return wordTokenizer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer: %s", name)
+	}
+}
+
+// approxTokenizer estimates the tokens of a text from its length, about 4 characters per token in English
+type approxTokenizer struct{}
+
+func (approxTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// wordTokenizer counts every word, number and punctuation mark as a token
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	n := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				n++
+			}
+			inWord = true
+		case unicode.IsSpace(r):
+			inWord = false
+		default:
+			n++
+			inWord = false
+		}
+	}
+	return n
+}
+
+// responseBody holds the fields of the OpenAI and Ollama response bodies the metrics are computed from
+type responseBody struct {
+	// OpenAI chat and completions APIs, the message for whole responses and the delta for chunks
+	Choices []struct {
+		Text    string `json:"text"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+
+	// Ollama generate and chat APIs, the counts are set on the last chunk
+	Response string `json:"response"`
+	Message  struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int  `json:"prompt_eval_count"`
+	EvalCount       int  `json:"eval_count"`
+	Done            bool `json:"done"`
+}
+
+// parseResponse extracts the generated text and the token usage from a response body,
+// either a JSON object or server-sent events carrying one each. usage is nil when the body reports none.
+func parseResponse(body string) (text string, usage *Usage) {
+	body = strings.TrimSpace(body)
+	var bodies []responseBody
+	var whole responseBody
+	if strings.HasPrefix(body, "{") && json.Unmarshal([]byte(body), &whole) == nil {
+		bodies = append(bodies, whole)
+	} else {
+		for _, event := range strings.Split(body, "\n") {
+			event = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(event), "data:"))
+			if !strings.HasPrefix(event, "{") {
+				// Empty line, [DONE] or another SSE field
+				continue
+			}
+			var rb responseBody
+			if json.Unmarshal([]byte(event), &rb) == nil {
+				bodies = append(bodies, rb)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for _, rb := range bodies {
+		for _, c := range rb.Choices {
+			sb.WriteString(c.Text)
+			sb.WriteString(c.Message.Content)
+			sb.WriteString(c.Delta.Content)
+		}
+		sb.WriteString(rb.Response)
+		sb.WriteString(rb.Message.Content)
+
+		switch {
+		case rb.Usage != nil:
+			usage = &Usage{PromptTokens: rb.Usage.PromptTokens, CompletionTokens: rb.Usage.CompletionTokens}
+		case rb.Done && (rb.PromptEvalCount > 0 || rb.EvalCount > 0):
+			usage = &Usage{PromptTokens: rb.PromptEvalCount, CompletionTokens: rb.EvalCount}
+		}
+	}
+	return sb.String(), usage
+}
+
+// requestBody holds the fields of the OpenAI and Ollama request payloads the prompt is read from
+type requestBody struct {
+	Prompt   json.RawMessage `json:"prompt"` // Completions and Ollama generate APIs
+	System   string          `json:"system"`
+	Messages []struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"` // Chat APIs
+}
+
+// promptText extracts the prompt sent in a request payload, empty if it has none
+func promptText(payload string) string {
+	var rb requestBody
+	if err := json.Unmarshal([]byte(payload), &rb); err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(rb.System)
+	sb.WriteString(contentText(rb.Prompt))
+	for _, m := range rb.Messages {
+		sb.WriteString(contentText(m.Content))
+	}
+	return sb.String()
+}
+
+// contentText returns the text of a content field, either a string, a list of strings
+// or a list of parts with a text field
+func contentText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range parts {
+		var p struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(part, &s) == nil {
+			sb.WriteString(s)
+		} else if json.Unmarshal(part, &p) == nil {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
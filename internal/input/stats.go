@@ -0,0 +1,74 @@
+package input
+
+import (
+	"math"
+	"sort"
+)
+
+// DistStats summarizes the distribution of a set of samples (e.g. per-request latencies)
+type DistStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Stddev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// computeDistStats returns the distribution summary of values, ignoring the missing (NaN) ones.
+// The values slice is filtered and sorted in place.
+func computeDistStats(values []float64) DistStats {
+	measured := values[:0]
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			measured = append(measured, v)
+		}
+	}
+	values = measured
+	if len(values) == 0 {
+		return nanDistStats()
+	}
+	sort.Float64s(values)
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sqSum float64
+	for _, v := range values {
+		sqSum += (v - mean) * (v - mean)
+	}
+
+	return DistStats{
+		Min:    values[0],
+		Max:    values[len(values)-1],
+		Stddev: math.Sqrt(sqSum / float64(len(values))),
+		P50:    percentile(values, 50),
+		P90:    percentile(values, 90),
+		P95:    percentile(values, 95),
+		P99:    percentile(values, 99),
+	}
+}
+
+// nanDistStats returns the summary of a distribution that could not be measured
+func nanDistStats() DistStats {
+	nan := math.NaN()
+	return DistStats{Min: nan, Max: nan, Stddev: nan, P50: nan, P90: nan, P95: nan, P99: nan}
+}
+
+// percentile returns the p-th percentile of sorted values using linear interpolation
+// between the closest ranks (same as numpy's default, which GenAI-Perf uses).
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
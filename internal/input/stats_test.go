@@ -0,0 +1,76 @@
+package input
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeDistStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   DistStats
+	}{
+		{"empty", nil, nanDistStats()},
+		{"only NaN", []float64{math.NaN(), math.NaN()}, nanDistStats()},
+		{"one value", []float64{3}, DistStats{Min: 3, Max: 3, P50: 3, P90: 3, P95: 3, P99: 3}},
+		{
+			"odd count",
+			[]float64{5, 1, 3},
+			DistStats{Min: 1, Max: 5, Stddev: math.Sqrt(8.0 / 3), P50: 3, P90: 4.6, P95: 4.8, P99: 4.96},
+		},
+		{
+			"even count",
+			[]float64{4, 1, 3, 2},
+			DistStats{Min: 1, Max: 4, Stddev: math.Sqrt(1.25), P50: 2.5, P90: 3.7, P95: 3.85, P99: 3.97},
+		},
+		{
+			"NaN filtered",
+			[]float64{math.NaN(), 5, 1, math.NaN(), 3},
+			DistStats{Min: 1, Max: 5, Stddev: math.Sqrt(8.0 / 3), P50: 3, P90: 4.6, P95: 4.8, P99: 4.96},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeDistStats(tt.values)
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"Min", got.Min, tt.want.Min},
+				{"Max", got.Max, tt.want.Max},
+				{"Stddev", got.Stddev, tt.want.Stddev},
+				{"P50", got.P50, tt.want.P50},
+				{"P90", got.P90, tt.want.P90},
+				{"P95", got.P95, tt.want.P95},
+				{"P99", got.P99, tt.want.P99},
+			} {
+				if math.IsNaN(f.got) != math.IsNaN(f.want) || math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 10},
+		{50, 30},
+		{90, 46},
+		{99, 49.6},
+		{100, 50},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile(%v, %v) = %v, want %v", sorted, tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); !math.IsNaN(got) {
+		t.Errorf("percentile(nil, 50) = %v, want NaN", got)
+	}
+}
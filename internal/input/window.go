@@ -0,0 +1,126 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// SteadyWindow is the window of an experiment over which its perf and energy are measured,
+// along with the requests measured within it
+type SteadyWindow struct {
+	Begin    time.Time
+	End      time.Time
+	Requests []Request
+}
+
+// checkSteadyState validates the steady-state window settings
+func checkSteadyState(sc config.SteadyStateConf) error {
+	switch sc.Window {
+	case "", config.WindowFull, config.WindowBoundaries, config.WindowTrim:
+	default:
+		return fmt.Errorf("unknown steady-state window: %s", sc.Window)
+	}
+	if sc.WarmupRequests < 0 || sc.CooldownRequests < 0 || sc.Warmup < 0 || sc.Cooldown < 0 {
+		return fmt.Errorf("steady-state warm-up and cool-down must not be negative")
+	}
+	return nil
+}
+
+// fullWindow returns the span of the experiment, from the first request to the last response, with all its requests
+func fullWindow(exp Experiment) SteadyWindow {
+	reqs := exp.Requests
+	lastReq := reqs[len(reqs)-1]
+	expBegin := reqs[0].Timestamp
+	expEnd := lastReq.ResponseTimestamps[len(lastReq.ResponseTimestamps)-1]
+	return SteadyWindow{Begin: time.Unix(0, expBegin), End: time.Unix(0, expEnd), Requests: reqs}
+}
+
+// NewSteadyWindow returns the steady-state window of the experiment selected by sc.
+// An error is returned when the window cannot be found or holds no request.
+func NewSteadyWindow(exp Experiment, sc config.SteadyStateConf) (SteadyWindow, error) {
+	if len(exp.Requests) == 0 {
+		return SteadyWindow{}, fmt.Errorf("no request in the experiment")
+	}
+	switch sc.Window {
+	case "", config.WindowFull:
+		return fullWindow(exp), nil
+	case config.WindowBoundaries:
+		return boundariesWindow(exp)
+	case config.WindowTrim:
+		return trimmedWindow(exp, sc)
+	default:
+		return SteadyWindow{}, fmt.Errorf("unknown steady-state window: %s", sc.Window)
+	}
+}
+
+// boundariesWindow returns the window from the first to the last window boundary of the profile export
+func boundariesWindow(exp Experiment) (SteadyWindow, error) {
+	wb := exp.WindowBoundaries
+	if len(wb) < 2 {
+		return SteadyWindow{}, fmt.Errorf("the profile has no window boundaries")
+	}
+	if !sort.SliceIsSorted(wb, func(i, j int) bool { return wb[i] < wb[j] }) || wb[0] == wb[len(wb)-1] {
+		return SteadyWindow{}, fmt.Errorf("invalid window boundaries %v", wb)
+	}
+	win := SteadyWindow{Begin: time.Unix(0, wb[0]), End: time.Unix(0, wb[len(wb)-1])}
+	for _, req := range exp.Requests {
+		if len(req.ResponseTimestamps) > 0 && req.Timestamp >= wb[0] && req.ResponseTimestamps[len(req.ResponseTimestamps)-1] <= wb[len(wb)-1] {
+			win.Requests = append(win.Requests, req)
+		}
+	}
+	if len(win.Requests) == 0 {
+		return SteadyWindow{}, fmt.Errorf("no request within the window boundaries")
+	}
+	return win, nil
+}
+
+// trimmedWindow returns the span of the requests left once the warm-up and cool-down of sc are trimmed
+// from the span of the requests: the requests sent during the warm-up or completed during the cool-down,
+// then the first requests sent and the last ones completed
+func trimmedWindow(exp Experiment, sc config.SteadyStateConf) (SteadyWindow, error) {
+	reqEnd := func(req Request) int64 { return req.ResponseTimestamps[len(req.ResponseTimestamps)-1] }
+	var reqs []Request
+	for _, req := range exp.Requests {
+		if len(req.ResponseTimestamps) > 0 {
+			reqs = append(reqs, req)
+		}
+	}
+	if len(reqs) == 0 {
+		return SteadyWindow{}, fmt.Errorf("no request responded")
+	}
+
+	// The last response is not necessarily the one of the last request sent
+	begin, end := reqs[0].Timestamp, reqEnd(reqs[0])
+	for _, req := range reqs {
+		begin, end = min(begin, req.Timestamp), max(end, reqEnd(req))
+	}
+	if run := time.Duration(end - begin); sc.Warmup+sc.Cooldown >= run {
+		return SteadyWindow{}, fmt.Errorf("the warm-up (%v) and the cool-down (%v) leave nothing of the %v run", sc.Warmup, sc.Cooldown, run)
+	}
+	begin, end = begin+sc.Warmup.Nanoseconds(), end-sc.Cooldown.Nanoseconds()
+	steady := reqs[:0]
+	for _, req := range reqs {
+		if req.Timestamp >= begin && reqEnd(req) <= end {
+			steady = append(steady, req)
+		}
+	}
+	if len(steady) == 0 {
+		return SteadyWindow{}, fmt.Errorf("no request between the warm-up (%v) and the cool-down (%v)", sc.Warmup, sc.Cooldown)
+	}
+	if len(steady) <= sc.WarmupRequests+sc.CooldownRequests {
+		return SteadyWindow{}, fmt.Errorf("no request left once %d warm-up and %d cool-down requests are trimmed from %d",
+			sc.WarmupRequests, sc.CooldownRequests, len(steady))
+	}
+
+	sort.SliceStable(steady, func(i, j int) bool { return steady[i].Timestamp < steady[j].Timestamp })
+	steady = steady[sc.WarmupRequests:]
+	sort.SliceStable(steady, func(i, j int) bool { return reqEnd(steady[i]) < reqEnd(steady[j]) })
+	steady = steady[:len(steady)-sc.CooldownRequests]
+	last := reqEnd(steady[len(steady)-1])
+	sort.SliceStable(steady, func(i, j int) bool { return steady[i].Timestamp < steady[j].Timestamp })
+
+	return SteadyWindow{Begin: time.Unix(0, steady[0].Timestamp), End: time.Unix(0, last), Requests: steady}, nil
+}
@@ -0,0 +1,166 @@
+package input
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/explorerray/itpe-report/config"
+)
+
+// request returns a request sent sent seconds after logStart and answered done seconds after it,
+// unanswered when done is negative
+func request(sent, done float64) Request {
+	req := Request{Timestamp: since(sent).UnixNano()}
+	if done >= 0 {
+		req.ResponseTimestamps = []int64{since((sent + done) / 2).UnixNano(), since(done).UnixNano()}
+	}
+	return req
+}
+
+// sentAt returns the send times of the requests in seconds after logStart
+func sentAt(reqs []Request) []float64 {
+	var sent []float64
+	for _, req := range reqs {
+		sent = append(sent, time.Unix(0, req.Timestamp).Sub(logStart).Seconds())
+	}
+	return sent
+}
+
+func TestTrimmedWindow(t *testing.T) {
+	// Four requests over 10s, the last response being the one of the third request
+	run := []Request{request(0, 3), request(1, 5), request(2, 10), request(4, 8)}
+
+	tests := []struct {
+		name       string
+		requests   []Request
+		sc         config.SteadyStateConf
+		begin, end float64   // Seconds after logStart
+		sent       []float64 // Send times of the measured requests
+		wantErr    string
+	}{
+		{name: "nothing trimmed", requests: run, begin: 0, end: 10, sent: []float64{0, 1, 2, 4}},
+		{
+			name: "warm-up and cool-down", requests: run, sc: config.SteadyStateConf{Warmup: time.Second, Cooldown: time.Second},
+			begin: 1, end: 8, sent: []float64{1, 4},
+		},
+		{
+			name: "warm-up and cool-down requests", requests: run, sc: config.SteadyStateConf{WarmupRequests: 1, CooldownRequests: 1},
+			begin: 1, end: 8, sent: []float64{1, 4},
+		},
+		{
+			name: "unanswered request", requests: append([]Request{request(0, -1)}, run[1:]...),
+			begin: 1, end: 10, sent: []float64{1, 2, 4},
+		},
+		{
+			name: "warm-up and cool-down longer than the run", requests: run,
+			sc:      config.SteadyStateConf{Warmup: 6 * time.Second, Cooldown: 5 * time.Second},
+			wantErr: "leave nothing of the 10s run",
+		},
+		{
+			name: "warm-up and cool-down as long as the run", requests: run,
+			sc:      config.SteadyStateConf{Warmup: 5 * time.Second, Cooldown: 5 * time.Second},
+			wantErr: "leave nothing of the 10s run",
+		},
+		{
+			name: "no request between the warm-up and the cool-down", requests: run,
+			sc:      config.SteadyStateConf{Warmup: 3 * time.Second, Cooldown: 3 * time.Second},
+			wantErr: "no request between the warm-up",
+		},
+		{
+			name: "every request trimmed", requests: run, sc: config.SteadyStateConf{WarmupRequests: 2, CooldownRequests: 2},
+			wantErr: "no request left",
+		},
+		{name: "no answered request", requests: []Request{request(0, -1)}, wantErr: "no request responded"},
+		{name: "no request", wantErr: "no request in the experiment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.sc.Window = config.WindowTrim
+			win, err := NewSteadyWindow(Experiment{Requests: tt.requests}, tt.sc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewSteadyWindow error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSteadyWindow failed: %v", err)
+			}
+			if !win.Begin.Equal(since(tt.begin)) || !win.End.Equal(since(tt.end)) {
+				t.Errorf("window = [%v, %v], want [%v, %v]", win.Begin, win.End, since(tt.begin), since(tt.end))
+			}
+			if got := sentAt(win.Requests); !slices.Equal(got, tt.sent) {
+				t.Errorf("requests sent at %v, want %v", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestBoundariesWindow(t *testing.T) {
+	run := []Request{request(0, 3), request(1, 5), request(2, 10), request(4, 8)}
+	boundaries := func(secs ...float64) []int64 {
+		var wb []int64
+		for _, s := range secs {
+			wb = append(wb, since(s).UnixNano())
+		}
+		return wb
+	}
+
+	tests := []struct {
+		name       string
+		requests   []Request
+		boundaries []int64
+		sent       []float64 // Send times of the measured requests
+		wantErr    string
+	}{
+		{name: "steady state", requests: run, boundaries: boundaries(1, 6, 9), sent: []float64{1, 4}},
+		{name: "whole run", requests: run, boundaries: boundaries(0, 10), sent: []float64{0, 1, 2, 4}},
+		{name: "no request in the steady state", requests: run, boundaries: boundaries(2.5, 7), wantErr: "no request within the window boundaries"},
+		{name: "no request", boundaries: boundaries(0, 10), wantErr: "no request in the experiment"},
+		{name: "no boundaries", requests: run, wantErr: "no window boundaries"},
+		{name: "single boundary", requests: run, boundaries: boundaries(1), wantErr: "no window boundaries"},
+		{name: "unsorted boundaries", requests: run, boundaries: boundaries(6, 1), wantErr: "invalid window boundaries"},
+		{name: "empty boundaries", requests: run, boundaries: boundaries(1, 1), wantErr: "invalid window boundaries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := Experiment{Requests: tt.requests, WindowBoundaries: tt.boundaries}
+			win, err := NewSteadyWindow(exp, config.SteadyStateConf{Window: config.WindowBoundaries})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewSteadyWindow error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSteadyWindow failed: %v", err)
+			}
+			wb := tt.boundaries
+			if win.Begin.UnixNano() != wb[0] || win.End.UnixNano() != wb[len(wb)-1] {
+				t.Errorf("window = [%v, %v], want the first and last boundaries", win.Begin, win.End)
+			}
+			if got := sentAt(win.Requests); !slices.Equal(got, tt.sent) {
+				t.Errorf("requests sent at %v, want %v", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestPerfExpMetricsWindowFallback(t *testing.T) {
+	// A warm-up and cool-down longer than the run fall back to the whole run
+	profile := &ProfileExport{Experiments: []Experiment{{Requests: []Request{request(0, 3), request(1, 5), request(2, 10)}}}}
+	sc := config.SteadyStateConf{Window: config.WindowTrim, Warmup: 8 * time.Second, Cooldown: 8 * time.Second}
+
+	var logs bytes.Buffer
+	em := perfExpMetrics(profile, GenAIPerfExpConf{Model: "mistral"}, sc, slog.New(slog.NewTextHandler(&logs, nil)))
+	if em.PerfM.TotalTimeSec != 10 || em.PerfM.NumRequests != 3 {
+		t.Errorf("metrics over %vs of %d requests, want the whole run of 10s and 3 requests", em.PerfM.TotalTimeSec, em.PerfM.NumRequests)
+	}
+	if !strings.Contains(logs.String(), "No steady-state window, measuring the whole experiment") {
+		t.Errorf("fallback not logged:\n%s", logs.String())
+	}
+}